@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/hex"
+)
+
+// dump hex dumps b, or as much of it as maxDumpSize allows.
+func dump(b []byte) string {
+	if len(b) == 0 {
+		return "(no bytes)\n"
+	}
+	if len(b) > maxDumpSize {
+		return hex.Dump(b[:maxDumpSize]) + "...\n"
+	}
+	return hex.Dump(b)
+}
+
+// ReceiveBytes returns everything the given function sends over UDP,
+// concatenated, as ReceiveString does, but as the bytes received.
+func (s *Server) ReceiveBytes(t TestingT, body fn, opts ...Option) []byte {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	return c.captureBytes(t, body, true, newCallConfig(opts))
+}
+
+// ReceiveBytes calls Server.ReceiveBytes on the default server.
+func ReceiveBytes(t TestingT, body fn, opts ...Option) []byte {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveBytes(t, body, opts...)
+}
+
+// ShouldReceiveBytes will fire a test error unless the given function sends
+// exactly the given bytes over UDP. It is ShouldReceiveOnly for binary
+// protocols, and reports both sides as hex dumps.
+func (s *Server) ShouldReceiveBytes(t TestingT, expected []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureBytes(t, body, true, newCallConfig(opts))
+	if !bytes.Equal(got, expected) {
+		c.printLocation(t)
+		c.errorF("Expected %d bytes:\n%s", len(expected), dump(expected))
+		c.errorF("But got %d bytes:\n%s", len(got), dump(got))
+	}
+}
+
+// ShouldReceiveBytes calls Server.ShouldReceiveBytes on the default server.
+func ShouldReceiveBytes(t TestingT, expected []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveBytes(t, expected, body, opts...)
+}
+
+// ShouldContainBytes will fire a test error unless what the given function
+// sends over UDP, concatenated, contains the given bytes. It is ShouldReceive
+// for binary protocols, and reports both sides as hex dumps.
+func (s *Server) ShouldContainBytes(t TestingT, sub []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureBytes(t, body, true, newCallConfig(opts))
+	if !bytes.Contains(got, sub) {
+		c.printLocation(t)
+		c.errorF("Expected to find %d bytes:\n%s", len(sub), dump(sub))
+		c.errorF("But got %d bytes:\n%s", len(got), dump(got))
+	}
+}
+
+// ShouldContainBytes calls Server.ShouldContainBytes on the default server.
+func ShouldContainBytes(t TestingT, sub []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldContainBytes(t, sub, body, opts...)
+}
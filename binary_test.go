@@ -0,0 +1,32 @@
+package udp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestShouldReceiveBytes(t *testing.T) {
+	udpClient := setup(t)
+	frame := []byte{0x00, 0xff, 0xfe, 0x00, 0x80}
+	send := func() {
+		udpClient.Write(frame[:3])
+		udpClient.Write(frame[3:])
+	}
+
+	ShouldReceiveBytes(t, frame, send)
+	if got := ReceiveBytes(t, send); !bytes.Equal(got, frame) {
+		t.Errorf("Expected %x, got %x", frame, got)
+	}
+	ShouldContainBytes(t, []byte{0xfe, 0x00}, send)
+
+	ft := &fakeT{}
+	ShouldReceiveBytes(ft, []byte{0x00, 0xff}, send)
+	ShouldContainBytes(ft, []byte{0x80, 0x00}, send)
+	if len(ft.errors) != 2 ||
+		!strings.Contains(ft.errors[0], "Expected 2 bytes:\n00000000  00 ff ") ||
+		!strings.Contains(ft.errors[0], "But got 5 bytes:\n00000000  00 ff fe 00 80 ") ||
+		!strings.Contains(ft.errors[1], "Expected to find 2 bytes:\n00000000  80 00 ") {
+		t.Errorf("Expected hex dumps of both sides, got %#v", ft.errors)
+	}
+}
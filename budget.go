@@ -0,0 +1,157 @@
+package udp
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// budgetShare is the fraction of its remaining budget, as 1/budgetShare,
+	// an assertion may wait for packets.
+	budgetShare = 10
+	// minBudgetTimeout is the shortest idle timeout a budget scales down to.
+	minBudgetTimeout = 100 * time.Microsecond
+)
+
+// AssertionTiming is how long one assertion made under a budget took.
+type AssertionTiming struct {
+	// Location is the file and line of the assertion.
+	Location string
+	Duration time.Duration
+}
+
+type budget struct {
+	total   time.Duration
+	used    time.Duration
+	timings []AssertionTiming
+}
+
+// budgets holds the budget of each TestingT given one, by pointer. n counts
+// them, so that assertions made while there are none skip the lookup.
+var budgets = struct {
+	sync.Mutex
+	m map[TestingT]*budget
+	n int32
+}{m: map[TestingT]*budget{}}
+
+// budgetKeyable reports whether t can key budgets: only pointers can, as other
+// types may not be hashable and have no identity across calls.
+func budgetKeyable(t TestingT) bool {
+	return t != nil && reflect.TypeOf(t).Kind() == reflect.Ptr
+}
+
+// lookupBudget returns t's budget, or nil if it has none. budgets must be
+// locked.
+func lookupBudget(t TestingT) *budget {
+	if atomic.LoadInt32(&budgets.n) == 0 || !budgetKeyable(t) {
+		return nil
+	}
+	return budgets.m[t]
+}
+
+type cleaner interface {
+	Cleanup(func())
+}
+
+// SetAssertionBudget limits the total time the assertions made with t may
+// take. Each assertion waits for packets for at most Timeout, scaled down to
+// a share of what is left of the budget, and once the budget is used up
+// assertions fail without running their body.
+//
+// t must be a pointer, such as a *testing.T. The budget is dropped when the
+// test ends if t has a Cleanup method, which testing.T has from Go 1.14;
+// otherwise it is kept until ClearAssertionBudget is called.
+func SetAssertionBudget(t TestingT, total time.Duration) {
+	if !budgetKeyable(t) {
+		t.Fatal(fmt.Sprintf("udp: SetAssertionBudget needs a pointer TestingT, got %T at %s", t, callerLocation()))
+		return
+	}
+	budgets.Lock()
+	if budgets.m[t] == nil {
+		atomic.AddInt32(&budgets.n, 1)
+	}
+	budgets.m[t] = &budget{total: total}
+	budgets.Unlock()
+	if c, ok := t.(cleaner); ok {
+		c.Cleanup(func() {
+			ClearAssertionBudget(t)
+		})
+	}
+}
+
+// ClearAssertionBudget drops t's budget, if it has one, so its assertions are
+// no longer limited and the budget no longer held on to.
+func ClearAssertionBudget(t TestingT) {
+	if !budgetKeyable(t) {
+		return
+	}
+	budgets.Lock()
+	defer budgets.Unlock()
+	if budgets.m[t] != nil {
+		delete(budgets.m, t)
+		atomic.AddInt32(&budgets.n, -1)
+	}
+}
+
+// BudgetUsage returns how long each assertion made with t took since
+// SetAssertionBudget, in the order they were made, to find the expensive ones.
+func BudgetUsage(t TestingT) []AssertionTiming {
+	budgets.Lock()
+	defer budgets.Unlock()
+	b := lookupBudget(t)
+	if b == nil {
+		return nil
+	}
+	return append([]AssertionTiming(nil), b.timings...)
+}
+
+// applyBudget scales the idle timeout of cfg to t's remaining budget. It
+// returns false, having failed the test, if the budget is used up.
+func applyBudget(t TestingT, s *Server, cfg *callConfig) bool {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if atomic.LoadInt32(&budgets.n) == 0 {
+		return true
+	}
+	budgets.Lock()
+	b := lookupBudget(t)
+	var total, used time.Duration
+	if b != nil {
+		total, used = b.total, b.used
+	}
+	budgets.Unlock()
+	if b == nil {
+		return true
+	}
+	remaining := total - used
+	if remaining <= 0 {
+		t.Fatal(fmt.Sprintf("udp: assertion budget exhausted: %v used of %v at %s", used, total, callerLocation()))
+		return false
+	}
+	idle := remaining / budgetShare
+	if idle < minBudgetTimeout {
+		idle = minBudgetTimeout
+	}
+	if idle < cfg.idleTimeout(s) {
+		cfg.timeout = idle
+	}
+	return true
+}
+
+// chargeBudget records the time since started against t's budget.
+func chargeBudget(t TestingT, started time.Time) {
+	if atomic.LoadInt32(&budgets.n) == 0 {
+		return
+	}
+	budgets.Lock()
+	defer budgets.Unlock()
+	if b := lookupBudget(t); b != nil {
+		d := time.Since(started)
+		b.used += d
+		b.timings = append(b.timings, AssertionTiming{callerLocation(), d})
+	}
+}
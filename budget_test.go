@@ -0,0 +1,66 @@
+package udp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssertionBudget(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	SetAssertionBudget(ft, 20*time.Millisecond)
+	ShouldReceiveOnly(ft, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if usage := BudgetUsage(ft); len(usage) != 1 || !strings.Contains(usage[0].Location, "budget_test.go:") {
+		t.Errorf("Expected the assertion's timing to be recorded, got %#v", usage)
+	}
+
+	ShouldReceiveOnly(ft, "foo", func() {
+		time.Sleep(20 * time.Millisecond)
+	})
+	ran := false
+	ShouldReceiveOnly(ft, "foo", func() {
+		ran = true
+	})
+	if ran || len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "assertion budget exhausted") {
+		t.Errorf("Expected the assertion to fail fast once the budget was used up, got %#v", ft.fatals)
+	}
+}
+
+// funcT is a TestingT that can't be hashed.
+type funcT struct {
+	report func(string)
+}
+
+func (f funcT) Errorf(format string, args ...interface{}) { f.report(fmt.Sprintf(format, args...)) }
+func (f funcT) Error(args ...interface{})                 { f.report(fmt.Sprint(args...)) }
+func (f funcT) Fatal(args ...interface{})                 { f.report(fmt.Sprint(args...)) }
+
+func TestAssertionBudgetUnhashable(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	SetAssertionBudget(ft, time.Second)
+	defer ClearAssertionBudget(ft)
+
+	reports := []string{}
+	ShouldReceiveOnly(funcT{func(s string) { reports = append(reports, s) }}, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if len(reports) != 0 {
+		t.Errorf("Expected a TestingT that can't be hashed to be usable, got %q", reports)
+	}
+	SetAssertionBudget(funcT{func(s string) { reports = append(reports, s) }}, time.Second)
+	if len(reports) != 1 || !strings.Contains(reports[0], "needs a pointer TestingT") {
+		t.Errorf("Expected a budget on a TestingT that isn't a pointer to be refused, got %q", reports)
+	}
+
+	ClearAssertionBudget(ft)
+	if usage := BudgetUsage(ft); usage != nil {
+		t.Errorf("Expected the budget to be cleared, got %#v", usage)
+	}
+}
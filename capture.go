@@ -0,0 +1,559 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Packet is a single datagram received by a listener.
+type Packet struct {
+	Payload []byte
+	// Time is when the packet was received: the kernel's timestamp where
+	// the platform provides one, otherwise when it was read. Packets are
+	// read while the body runs, so either closely follows when they were
+	// sent.
+	Time time.Time
+	// Listener is the index, among the addresses captured from, of the
+	// address the packet was sent to.
+	Listener int
+	// From is the address the packet was sent from.
+	From *net.UDPAddr
+
+	// token is the capture token the packet was tagged with, if any.
+	token string
+	seq   int64
+	// ttl is the packet's IP TTL, or -1 if the capture didn't ask for it or
+	// it can't be determined.
+	ttl int
+}
+
+// MergePolicy decides the order of packets captured from several addresses.
+type MergePolicy int
+
+const (
+	// ByTimestamp orders packets by Time, then by Listener, then by the order
+	// they were read in. With kernel timestamps this is deterministic for
+	// packets sent in a known order.
+	ByTimestamp MergePolicy = iota
+	// ByArrival orders packets in the order they were read in, which
+	// depends on how the readers of each address were scheduled.
+	ByArrival
+)
+
+// WithMerge sets how ReceiveCaptureFrom orders the packets of several
+// addresses. It defaults to ByTimestamp.
+func WithMerge(policy MergePolicy) Option {
+	return func(c *callConfig) {
+		c.merge = policy
+	}
+}
+
+// Capture holds the datagrams received during one assertion, in the order they
+// arrived.
+type Capture struct {
+	Packets []Packet
+	// Transforms lists the transformations, such as Shuffled, a capture was
+	// derived with, in the order applied. It is empty for a capture as
+	// received, and anything reporting on a capture should show it.
+	Transforms []string
+
+	partitionBy func(payload []byte) string
+}
+
+// getCapture returns every packet the given function sends, captured with a
+// copy of cfg, so that an assertion capturing more than once starts each
+// capture with the options it was given.
+func (c *call) getCapture(t TestingT, body fn, cfg *callConfig) *Capture {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	copied := *cfg
+	return &Capture{Packets: c.capturePackets(t, body, &copied)}
+}
+
+// ReceiveCapture returns every packet the given function sends, for building
+// assertions this package doesn't provide.
+func (s *Server) ReceiveCapture(t TestingT, body fn, opts ...Option) *Capture {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	return &Capture{Packets: c.capturePackets(t, body, cfg), partitionBy: cfg.partitionBy}
+}
+
+// ReceiveCapture calls Server.ReceiveCapture on the default server.
+func ReceiveCapture(t TestingT, body fn, opts ...Option) *Capture {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveCapture(t, body, opts...)
+}
+
+// ReceivePackets returns the payload of every datagram the given function
+// sends, one string per datagram in the order they arrived. Empty datagrams
+// are kept as empty strings.
+func (s *Server) ReceivePackets(t TestingT, body fn, opts ...Option) []string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	return payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+}
+
+// ReceivePackets calls Server.ReceivePackets on the default server.
+func ReceivePackets(t TestingT, body fn, opts ...Option) []string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceivePackets(t, body, opts...)
+}
+
+// ListenUntil returns every packet received from when it is called until
+// duration has passed, or ctx is done, however many packets arrive and
+// however long the gaps between them. A body that runs for longer is not
+// interrupted, but reading stops when it returns.
+func (s *Server) ListenUntil(t TestingT, ctx context.Context, duration time.Duration, body fn) []Packet {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	return c.capturePackets(t, body, &callConfig{ctx: ctx, until: time.Now().Add(duration)})
+}
+
+// ListenUntil calls Server.ListenUntil on the default server.
+func ListenUntil(t TestingT, ctx context.Context, duration time.Duration, body fn) []Packet {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ListenUntil(t, ctx, duration, body)
+}
+
+// ListenN returns the first n packets received, reading nothing after them,
+// so that packets from whatever runs next are left alone. It waits for them
+// for as long as ctx allows, and returns fewer if ctx is done first. A ctx
+// that is nil, or can never be done, waits only until nothing has been
+// received for Timeout. For n <= 0 it runs body and returns nothing.
+func (s *Server) ListenN(t TestingT, ctx context.Context, n int, body fn) []Packet {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if n <= 0 {
+		body()
+		return nil
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	return c.capturePackets(t, body, &callConfig{ctx: ctx, packetLimit: n})
+}
+
+// ListenN calls Server.ListenN on the default server.
+func ListenN(t TestingT, ctx context.Context, n int, body fn) []Packet {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ListenN(t, ctx, n, body)
+}
+
+// ShouldReceiveWithCallback calls cb with every packet the given function
+// sends, in the order they arrived, and fires a test error if it sends none.
+// The callbacks run on the caller's goroutine once the capture has ended, so
+// they may fail the test or make assertions of their own.
+func (s *Server) ShouldReceiveWithCallback(t TestingT, cb func(t TestingT, pkt Packet), body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	if len(packets) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected packets for the callback, but got no data")
+		return
+	}
+	for _, p := range packets {
+		cb(t, p)
+	}
+}
+
+// ShouldReceiveWithCallback calls Server.ShouldReceiveWithCallback on the
+// default server.
+func ShouldReceiveWithCallback(t TestingT, cb func(t TestingT, pkt Packet), body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveWithCallback(t, cb, body, opts...)
+}
+
+// ReceiveCaptureFrom listens on every one of the given addresses while the
+// given function runs and returns all the packets sent to them, merged into
+// one capture according to WithMerge. If the test is failed before the body
+// runs, the capture returned is empty rather than nil.
+func (s *Server) ReceiveCaptureFrom(t TestingT, addrs []string, body fn, opts ...Option) *Capture {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	if !applyBudget(t, c.s, cfg) {
+		return &Capture{}
+	}
+	defer chargeBudget(t, time.Now())
+	conns, ok := listenAll(t, addrs)
+	defer closeAll(conns)
+	if !ok {
+		return &Capture{}
+	}
+	packets, _ := c.readPacketsFrom(conns, body, cfg)
+	return &Capture{Packets: packets, partitionBy: cfg.partitionBy}
+}
+
+// listenAll binds every one of addrs. It returns false, having failed the
+// test, if one of them can't be bound; the conns bound so far are returned
+// either way, for closeAll.
+func listenAll(t TestingT, addrs []string) ([]*net.UDPConn, bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	conns := make([]*net.UDPConn, 0, len(addrs))
+	for _, a := range addrs {
+		resAddr, err := net.ResolveUDPAddr("udp", a)
+		if err != nil {
+			t.Fatal(err)
+			return conns, false
+		}
+		conn, err := net.ListenUDP("udp", resAddr)
+		if err != nil {
+			t.Fatal(err)
+			return conns, false
+		}
+		conns = append(conns, conn)
+	}
+	return conns, true
+}
+
+func closeAll(conns []*net.UDPConn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// ReceiveCaptureFrom calls Server.ReceiveCaptureFrom on the default server.
+func ReceiveCaptureFrom(t TestingT, addrs []string, body fn, opts ...Option) *Capture {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveCaptureFrom(t, addrs, body, opts...)
+}
+
+func (c *call) capturePackets(t TestingT, body fn, cfg *callConfig) []Packet {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !applyBudget(t, c.s, cfg) {
+		return nil
+	}
+	defer chargeBudget(t, time.Now())
+	if !c.start(t, cfg) {
+		return nil
+	}
+	defer c.stop(t)
+	defer c.reportForeign(t)
+	if cfg.ttl {
+		if err := enableTTL(c.listener); err != nil {
+			t.Fatal(err)
+			return nil
+		}
+	}
+	packets, _ := c.readPacketsFrom([]*net.UDPConn{c.listener}, body, cfg)
+	return packets
+}
+
+// readPacketsFrom reads from every conn on its own goroutine while body runs,
+// so that every packet is timestamped as it arrives rather than after the
+// fact. Each conn is read until it has been idle for Timeout after the body
+// returns, or until packetLimit packets have been read if it is set, waiting
+// for them until ctx is done if it can be. The error
+// that ended reading the first conn is returned too; errors other than
+// timeouts have already been reported. Capture tokens are stripped from the
+// packets, and with WithExclusiveUse those not the test's own are set aside
+// in c.foreign.
+func (c *call) readPacketsFrom(conns []*net.UDPConn, body fn, cfg *callConfig) ([]Packet, error) {
+	c.fatal = cfg.fatal
+	var lingerEnd, waitEnd time.Time
+	idle := cfg.idleTimeout(c.s)
+	// untilCtx waits for packetLimit packets for as long as ctx allows, rather
+	// than until the conns are idle, if ctx can be done at all.
+	untilCtx := cfg.packetLimit > 0 && cfg.ctx != nil && cfg.ctx.Done() != nil
+	token := c.s.captureToken()
+	events := cfg.events
+	started := time.Now()
+	if events != nil {
+		c.logMu.Lock()
+		c.events = events
+		c.logMu.Unlock()
+		for i, conn := range conns {
+			events.event("listener_bound", "listener", i, "addr", conn.LocalAddr().String())
+		}
+	}
+	// bodyDone is 1 once the body has returned, and 2 once the readers are
+	// to stop at once.
+	var bodyDone int32
+	var seq int64
+	received := make([][]Packet, len(conns))
+	var firstErr error
+	done := make(chan struct{})
+	stopReaders := func() {
+		atomic.StoreInt32(&bodyDone, 2)
+		for _, conn := range conns {
+			conn.SetReadDeadline(time.Now())
+		}
+	}
+	for i, conn := range conns {
+		conn.SetReadDeadline(time.Time{})
+		enableTimestamps(conn)
+		go func(i int, conn *net.UDPConn) {
+			defer func() { done <- struct{}{} }()
+			message := make([]byte, maxDatagramSize)
+			oob := make([]byte, 128)
+			// armed is set when this reader has itself started an idle
+			// window since its last packet. Until then a timeout may
+			// only mean the goroutine was scheduled late, with packets
+			// still queued.
+			armed := false
+			for {
+				n, oobn, _, src, err := conn.ReadMsgUDP(message, oob)
+				if err != nil {
+					if i == 0 {
+						firstErr = err
+					}
+					if !isTimeout(err) {
+						c.errorF("Error reading udp data: %v", err)
+					} else if !armed && atomic.LoadInt32(&bodyDone) != 2 {
+						conn.SetReadDeadline(readDeadline(idle, lingerEnd, waitEnd))
+						armed = true
+						continue
+					}
+					return
+				}
+				at, ok := parseTimestamp(oob[:oobn])
+				if !ok {
+					at = time.Now()
+				}
+				ttl, ok := parseTTL(oob[:oobn])
+				if !ok {
+					ttl = -1
+				}
+				payload, tagged := splitToken(message[:n])
+				received[i] = append(received[i], Packet{
+					Payload:  append([]byte(nil), payload...),
+					Time:     at,
+					Listener: i,
+					From:     src,
+					token:    tagged,
+					seq:      atomic.AddInt64(&seq, 1),
+					ttl:      ttl,
+				})
+				if events != nil {
+					events.event("packet_received", "listener", i, "size", n, "src", src.String())
+				}
+				if cfg.packetLimit > 0 && atomic.LoadInt64(&seq) >= int64(cfg.packetLimit) {
+					stopReaders()
+					return
+				}
+				if untilCtx {
+					continue
+				}
+				armed = false
+				if atomic.LoadInt32(&bodyDone) == 1 {
+					conn.SetReadDeadline(readDeadline(idle, lingerEnd, waitEnd))
+				}
+			}
+		}(i, conn)
+	}
+	if cfg.ctx != nil {
+		watching := make(chan struct{})
+		watched := make(chan struct{})
+		go func() {
+			defer close(watched)
+			select {
+			case <-cfg.ctx.Done():
+				stopReaders()
+			case <-watching:
+			}
+		}()
+		defer func() {
+			close(watching)
+			<-watched
+		}()
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				// Stop the readers before the panic closes their conns.
+				stopReaders()
+				for range conns {
+					<-done
+				}
+				panic(r)
+			}
+		}()
+		body()
+	}()
+	lingerEnd, waitEnd = cfg.lingerEnd(), cfg.waitEnd()
+	if atomic.CompareAndSwapInt32(&bodyDone, 0, 1) && !untilCtx {
+		for _, conn := range conns {
+			conn.SetReadDeadline(readDeadline(idle, lingerEnd, waitEnd))
+		}
+	}
+	for range conns {
+		<-done
+	}
+
+	packets := []Packet{}
+	for _, r := range received {
+		packets = append(packets, r...)
+	}
+	if len(conns) > 1 {
+		mergePackets(packets, cfg.merge)
+	}
+	if cfg.received != nil {
+		cfg.received(packets)
+	}
+	if cfg.exclusiveUse {
+		for _, p := range packets {
+			if !isOwn(p, token, cfg.allowedPorts) {
+				c.foreign = append(c.foreign, p)
+			}
+		}
+	}
+	if events != nil {
+		size := 0
+		for _, p := range packets {
+			size += len(p.Payload)
+		}
+		events.event("capture_closed", "packets", len(packets), "bytes", size, "duration", time.Since(started))
+	}
+	if cfg.ledger != nil {
+		cfg.ledger.record(callerLocation(), packets)
+	}
+	return packets, firstErr
+}
+
+func mergePackets(packets []Packet, policy MergePolicy) {
+	sort.SliceStable(packets, func(i, j int) bool {
+		a, b := packets[i], packets[j]
+		if policy == ByTimestamp {
+			if !a.Time.Equal(b.Time) {
+				return a.Time.Before(b.Time)
+			}
+			if a.Listener != b.Listener {
+				return a.Listener < b.Listener
+			}
+		}
+		return a.seq < b.seq
+	})
+}
+
+// Bytes returns every packet in the capture, concatenated.
+func (c *Capture) Bytes() []byte {
+	var b []byte
+	for _, p := range c.Packets {
+		b = append(b, p.Payload...)
+	}
+	return b
+}
+
+// Payloads returns the payload of every packet in the capture, in order.
+func (c *Capture) Payloads() [][]byte {
+	payloads := make([][]byte, len(c.Packets))
+	for i, p := range c.Packets {
+		payloads[i] = p.Payload
+	}
+	return payloads
+}
+
+// String returns every packet in the capture, concatenated.
+func (c *Capture) String() string {
+	return string(c.Bytes())
+}
+
+// Span locates a match within one packet of a capture: Payload[Start:End] of
+// Packets[Packet].
+type Span struct {
+	Packet int
+	Start  int
+	End    int
+}
+
+// FindAll returns every non-overlapping occurrence of substr within a single
+// packet, in capture order. Occurrences spanning two packets, which the
+// assertions on the concatenated capture would see, are not reported.
+func (c *Capture) FindAll(substr string) []Span {
+	var spans []Span
+	if substr == "" {
+		return spans
+	}
+	for i, p := range c.Packets {
+		for off := 0; ; {
+			n := bytes.Index(p.Payload[off:], []byte(substr))
+			if n < 0 {
+				break
+			}
+			spans = append(spans, Span{i, off + n, off + n + len(substr)})
+			off += n + len(substr)
+		}
+	}
+	return spans
+}
+
+// FindAllRe returns every match of re within a single packet, in capture
+// order.
+func (c *Capture) FindAllRe(re *regexp.Regexp) []Span {
+	var spans []Span
+	for i, p := range c.Packets {
+		for _, loc := range re.FindAllIndex(p.Payload, -1) {
+			spans = append(spans, Span{i, loc[0], loc[1]})
+		}
+	}
+	return spans
+}
+
+// NearMatch returns where the longest part of substr found in the capture is,
+// for showing how close a missing string came to matching. It returns false
+// if no byte of substr was received.
+func (c *Capture) NearMatch(substr string) (Span, bool) {
+	var best Span
+	// prev[j] and cur[j] hold the length of the common suffix of the
+	// payload so far and substr[:j].
+	prev := make([]int, len(substr)+1)
+	cur := make([]int, len(substr)+1)
+	for i, p := range c.Packets {
+		for j := range prev {
+			prev[j] = 0
+		}
+		for k, b := range p.Payload {
+			for j := 1; j <= len(substr); j++ {
+				cur[j] = 0
+				if substr[j-1] == b {
+					cur[j] = prev[j-1] + 1
+					if cur[j] > best.End-best.Start {
+						best = Span{i, k + 1 - cur[j], k + 1}
+					}
+				}
+			}
+			prev, cur = cur, prev
+		}
+	}
+	return best, best.End > best.Start
+}
@@ -0,0 +1,195 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReceiveCaptureFromMergesDeterministically(t *testing.T) {
+	addrs := freeAddrs(t, 2)
+	clients := make([]net.Conn, len(addrs))
+	for i, a := range addrs {
+		conn, err := net.Dial("udp", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		clients[i] = conn
+	}
+
+	// Linux only starts timestamping packets shortly after the first socket
+	// asks for it, so the very first capture may fall back to read times.
+	ReceiveCaptureFrom(t, addrs, func() {
+		fmt.Fprint(clients[0], "warm up")
+	})
+
+	expected := ""
+	for i := 0; i < 10; i++ {
+		expected += fmt.Sprintf("a%d b%d ", i, i)
+	}
+	for run := 0; run < 100; run++ {
+		c := ReceiveCaptureFrom(t, addrs, func() {
+			for i := 0; i < 10; i++ {
+				fmt.Fprintf(clients[0], "a%d ", i)
+				fmt.Fprintf(clients[1], "b%d ", i)
+			}
+		})
+		if got := c.String(); got != expected {
+			t.Fatalf("Run %d: expected merged transcript %#v but got %#v", run, expected, got)
+		}
+		for _, p := range c.Packets {
+			if strings.HasPrefix(string(p.Payload), "a") != (p.Listener == 0) {
+				t.Fatalf("Run %d: packet %#v tagged with listener %d", run, p.Payload, p.Listener)
+			}
+		}
+	}
+}
+
+func TestReceiveCaptureFromByArrival(t *testing.T) {
+	addrs := freeAddrs(t, 2)
+	c := ReceiveCaptureFrom(t, addrs, func() {
+		for _, a := range addrs {
+			conn, err := net.Dial("udp", a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Write([]byte(a))
+			conn.Close()
+		}
+	}, WithMerge(ByArrival))
+	if len(c.Packets) != 2 || c.Packets[0].seq > c.Packets[1].seq {
+		t.Errorf("Expected both packets in read order, got %#v", c.Packets)
+	}
+}
+
+func TestReceiveCaptureFromFailureIsEmpty(t *testing.T) {
+	addrs := freeAddrs(t, 1)
+	taken, err := net.ListenPacket("udp", addrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	ft := &fakeT{}
+	c := ReceiveCaptureFrom(ft, addrs, func() {})
+	if c == nil || len(c.Packets) != 0 || len(ft.fatals) != 1 {
+		t.Errorf("Expected an empty capture and a failure, got %#v %#v", c, ft.fatals)
+	}
+}
+
+func TestPanickingBodyStopsReaders(t *testing.T) {
+	setup(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected the body's panic to propagate, got %#v", r)
+			}
+		}()
+		ReceiveCapture(t, func() {
+			panic("boom")
+		})
+	}()
+
+	ft := &fakeT{}
+	ShouldReceiveNothing(ft, func() {})
+	if len(ft.errors) != 0 {
+		t.Errorf("Expected no read errors to leak from the panicking capture, got %#v", ft.errors)
+	}
+}
+
+func TestCaptureSpans(t *testing.T) {
+	c := &Capture{Packets: []Packet{
+		{Payload: []byte("foo.bar:1|c")},
+		{Payload: []byte("foo.baz:2|c foo.bar:3|c")},
+	}}
+
+	if got, want := c.FindAll("foo.bar"), []Span{{0, 0, 7}, {1, 12, 19}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected FindAll spans %v, got %v", want, got)
+	}
+	if got, want := c.FindAllRe(regexp.MustCompile(`:\d`)), []Span{{0, 7, 9}, {1, 7, 9}, {1, 19, 21}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected FindAllRe spans %v, got %v", want, got)
+	}
+	if got, ok := c.NearMatch("foo.baz:9|g"); !ok || got != (Span{1, 0, 8}) {
+		t.Errorf("Expected the near match to cover \"foo.baz:\", got %v %v", got, ok)
+	}
+	if _, ok := c.NearMatch("qwe"); ok {
+		t.Error("Expected no near match when no byte was received")
+	}
+}
+
+func TestReceivePackets(t *testing.T) {
+	udpClient := setup(t)
+
+	got := ReceivePackets(t, func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte{})
+		udpClient.Write([]byte("bar"))
+	})
+	if !reflect.DeepEqual(got, []string{"foo", "", "bar"}) {
+		t.Errorf("Expected one string per datagram, got %#v", got)
+	}
+}
+
+func TestCapturePayloads(t *testing.T) {
+	udpClient := setup(t)
+
+	c := ReceiveCapture(t, func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+	if got := c.Payloads(); !reflect.DeepEqual(got, [][]byte{[]byte("foo"), []byte("bar")}) {
+		t.Errorf("Expected each datagram's bytes, got %q", got)
+	}
+}
+
+func TestShouldReceiveWithCallback(t *testing.T) {
+	udpClient := setup(t)
+
+	var got []string
+	ShouldReceiveWithCallback(t, func(t TestingT, pkt Packet) {
+		got = append(got, string(pkt.Payload))
+		if len(got) == 1 {
+			ShouldReceiveOnly(t, "nested", func() {
+				udpClient.Write([]byte("nested"))
+			})
+		}
+	}, func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+	if !reflect.DeepEqual(got, []string{"foo", "bar"}) {
+		t.Errorf("Expected a callback for each packet, got %#v", got)
+	}
+
+	ft := &fakeT{}
+	ShouldReceiveWithCallback(ft, func(TestingT, Packet) {
+		t.Error("Expected no callback without packets")
+	}, func() {})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "got no data") {
+		t.Errorf("Expected an empty capture to fail, got %#v", ft.errors)
+	}
+}
+
+func TestLargePacketsAreNotTruncated(t *testing.T) {
+	udpClient := setup(t)
+
+	sizes := []int{20000, 30000, 60000}
+	got := ReceivePackets(t, func() {
+		for i, n := range sizes {
+			udpClient.Write([]byte(strings.Repeat(string(rune('a'+i)), n)))
+		}
+	})
+	if len(got) != len(sizes) {
+		t.Fatalf("Expected %d packets, got %d", len(sizes), len(got))
+	}
+	for i, n := range sizes {
+		if want := strings.Repeat(string(rune('a'+i)), n); got[i] != want {
+			t.Errorf("Expected packet %d to be %d bytes of %q, got %d bytes", i, n, want[0], len(got[i]))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Command udpdoctor checks how UDP behaves on this machine, running the same
+// probes as udp.Doctor, and exits non-zero if a required one fails:
+//
+//	udpdoctor -require ipv6,multicast
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	udp "github.com/urjitbhatia/go-udp-testing"
+)
+
+// reporter prints what Doctor reports, in place of a test.
+type reporter struct{}
+
+func (reporter) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (reporter) Error(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+}
+
+func (reporter) Fatal(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
+
+func main() {
+	require := flag.String("require", "", "comma separated probes that must pass, besides bind")
+	flag.Parse()
+
+	var required []string
+	for _, name := range strings.Split(*require, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			required = append(required, name)
+		}
+	}
+	report := udp.Doctor(reporter{}, udp.WithRequiredProbes(required...))
+	fmt.Print(report)
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package udp
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// maxSnippetSize bounds how much of an unexplained region is quoted.
+const maxSnippetSize = 64
+
+// A SpanClaimer claims the parts of a capture it explains.
+type SpanClaimer interface {
+	Claim(c *Capture) []Span
+}
+
+// SpanClaimerFunc adapts a function to a SpanClaimer.
+type SpanClaimerFunc func(c *Capture) []Span
+
+// Claim calls f(c).
+func (f SpanClaimerFunc) Claim(c *Capture) []Span {
+	return f(c)
+}
+
+// ClaimString returns a SpanClaimer claiming every occurrence of s.
+func ClaimString(s string) SpanClaimer {
+	return SpanClaimerFunc(func(c *Capture) []Span {
+		return c.FindAll(s)
+	})
+}
+
+// ClaimRegexp returns a SpanClaimer claiming every match of re.
+func ClaimRegexp(re *regexp.Regexp) SpanClaimer {
+	return SpanClaimerFunc(func(c *Capture) []Span {
+		return c.FindAllRe(re)
+	})
+}
+
+// WithIgnoreWhitespace lets ShouldExplainEntireCapture leave regions made up
+// only of spaces, tabs and newlines unclaimed.
+func WithIgnoreWhitespace() Option {
+	return func(c *callConfig) {
+		c.ignoreWhitespace = true
+	}
+}
+
+// invalidSpan is a span a claimer claimed outside the capture.
+type invalidSpan struct {
+	claimer int
+	span    Span
+}
+
+// unclaimedSpans returns the regions of the capture no claimer claimed, and
+// the spans claimed that are not in the capture, which are ignored.
+func unclaimedSpans(c *Capture, claimers []SpanClaimer, ignoreWhitespace bool) ([]Span, []invalidSpan) {
+	claimed := make([][]bool, len(c.Packets))
+	for i, p := range c.Packets {
+		claimed[i] = make([]bool, len(p.Payload))
+	}
+	var invalid []invalidSpan
+	for i, cl := range claimers {
+		for _, s := range cl.Claim(c) {
+			if s.Packet < 0 || s.Packet >= len(c.Packets) || s.Start < 0 || s.Start > s.End || s.End > len(c.Packets[s.Packet].Payload) {
+				invalid = append(invalid, invalidSpan{i, s})
+				continue
+			}
+			for k := s.Start; k < s.End; k++ {
+				claimed[s.Packet][k] = true
+			}
+		}
+	}
+
+	var spans []Span
+	for i, p := range c.Packets {
+		for k := 0; k < len(p.Payload); {
+			if claimed[i][k] {
+				k++
+				continue
+			}
+			end := k
+			for end < len(p.Payload) && !claimed[i][end] {
+				end++
+			}
+			if !ignoreWhitespace || len(bytes.TrimSpace(p.Payload[k:end])) > 0 {
+				spans = append(spans, Span{i, k, end})
+			}
+			k = end
+		}
+	}
+	return spans, invalid
+}
+
+// ShouldExplainEntireCapture will fire a test error if any byte the given
+// function sends over UDP is not claimed by at least one of the claimers. It
+// lists every unclaimed region, and every span a claimer claimed outside the
+// capture, which fails it too.
+func (s *Server) ShouldExplainEntireCapture(t TestingT, claimers []SpanClaimer, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	capture := &Capture{Packets: c.capturePackets(t, body, cfg)}
+
+	unclaimed, invalid := unclaimedSpans(capture, claimers, cfg.ignoreWhitespace)
+	if len(unclaimed) == 0 && len(invalid) == 0 {
+		return
+	}
+	c.printLocation(t)
+	for _, bad := range invalid {
+		c.errorF("Claimer %d claimed a span outside the capture: packet %d at [%d:%d]", bad.claimer, bad.span.Packet, bad.span.Start, bad.span.End)
+	}
+	for _, span := range unclaimed {
+		snippet := capture.Packets[span.Packet].Payload[span.Start:span.End]
+		if len(snippet) > maxSnippetSize {
+			snippet = snippet[:maxSnippetSize]
+		}
+		c.errorF("Unexplained data in packet %d at [%d:%d]: %q", span.Packet, span.Start, span.End, snippet)
+	}
+}
+
+// ShouldExplainEntireCapture calls Server.ShouldExplainEntireCapture on the
+// default server.
+func ShouldExplainEntireCapture(t TestingT, claimers []SpanClaimer, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldExplainEntireCapture(t, claimers, body, opts...)
+}
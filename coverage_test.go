@@ -0,0 +1,44 @@
+package udp
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShouldExplainEntireCapture(t *testing.T) {
+	udpClient := setup(t)
+	claimers := []SpanClaimer{
+		ClaimRegexp(regexp.MustCompile(`[a-z.]+:\d+\|c`)),
+		ClaimString("\n"),
+	}
+
+	ShouldExplainEntireCapture(t, claimers, func() {
+		udpClient.Write([]byte("api.hits:1|c\napi.errors:2|c\n"))
+	})
+
+	ShouldExplainEntireCapture(t, claimers[:1], func() {
+		udpClient.Write([]byte("api.hits:1|c \n api.errors:2|c"))
+	}, WithIgnoreWhitespace())
+
+	ft := &fakeT{}
+	ShouldExplainEntireCapture(ft, claimers, func() {
+		udpClient.Write([]byte("api.hits:1|c\n"))
+		udpClient.Write([]byte("api.hits:1|c garbage"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], `Unexplained data in packet 1 at [12:20]: " garbage"`) {
+		t.Errorf("Expected the unclaimed region to be listed, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	outside := SpanClaimerFunc(func(c *Capture) []Span {
+		return []Span{{Packet: 0, Start: 0, End: 3}, {Packet: 1, Start: 0, End: 1}, {Packet: 0, Start: 2, End: 9}}
+	})
+	ShouldExplainEntireCapture(ft, []SpanClaimer{outside}, func() {
+		udpClient.Write([]byte("abc"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Claimer 0 claimed a span outside the capture: packet 1 at [0:1]") ||
+		!strings.Contains(ft.errors[0], "packet 0 at [2:9]") || strings.Contains(ft.errors[0], "Unexplained") {
+		t.Errorf("Expected the spans outside the capture to be reported, got %#v", ft.errors)
+	}
+}
@@ -0,0 +1,292 @@
+package udp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The probes Doctor runs, by name.
+const (
+	ProbeBind        = "bind"
+	ProbeLatency     = "latency"
+	ProbeMaxDatagram = "max-datagram"
+	ProbeReadBuffer  = "read-buffer"
+	ProbeIPv6        = "ipv6"
+	ProbeMulticast   = "multicast"
+	ProbeUnixgram    = "unixgram"
+)
+
+// doctorTimeout is how long a probe waits for each of its packets.
+const doctorTimeout = time.Second
+
+// ProbeResult is the outcome of one of Doctor's probes.
+type ProbeResult struct {
+	Name string
+	// Err is why the probe failed, or nil if it passed.
+	Err error
+	// Detail describes what the probe found, such as a latency distribution
+	// or a size limit.
+	Detail string
+	// Required is set for the probes WithRequiredProbes named.
+	Required bool
+}
+
+// DoctorReport holds the result of every probe, in the order they ran.
+type DoctorReport struct {
+	Results []ProbeResult
+}
+
+// OK reports whether every required probe passed.
+func (r *DoctorReport) OK() bool {
+	for _, res := range r.Results {
+		if res.Required && res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the report as one line per probe.
+func (r *DoctorReport) String() string {
+	var b bytes.Buffer
+	for _, res := range r.Results {
+		status := "ok"
+		if res.Err != nil {
+			status = "FAIL"
+			if !res.Required {
+				status = "warn"
+			}
+		}
+		fmt.Fprintf(&b, "%-12s %-4s %s", res.Name, status, res.Detail)
+		if res.Err != nil {
+			fmt.Fprintf(&b, " (%v)", res.Err)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// WithRequiredProbes makes Doctor fail the test if any of the named probes
+// fails. Other probes are only reported.
+func WithRequiredProbes(names ...string) Option {
+	return func(c *callConfig) {
+		c.requiredProbes = append(c.requiredProbes, names...)
+	}
+}
+
+// Doctor probes how UDP behaves on this machine, so that a suite can fail
+// fast from TestMain with one clear diagnosis rather than with many puzzling
+// assertion failures. Every probe is run and the report is logged, if t can
+// log; only the probes named by WithRequiredProbes fail the test. ProbeBind
+// is always required, and a required name that is no probe fails, so that a
+// typo can't let a check pass unnoticed.
+func Doctor(t TestingT, opts ...Option) *DoctorReport {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	cfg := newCallConfig(opts)
+	required := map[string]bool{ProbeBind: true}
+	for _, name := range cfg.requiredProbes {
+		required[name] = true
+	}
+
+	probes := []struct {
+		name  string
+		probe func() (string, error)
+	}{
+		{ProbeBind, probeBind},
+		{ProbeLatency, probeLatency},
+		{ProbeMaxDatagram, probeMaxDatagram},
+		{ProbeReadBuffer, probeReadBuffer},
+		{ProbeIPv6, probeIPv6},
+		{ProbeMulticast, probeMulticast},
+		{ProbeUnixgram, probeUnixgram},
+	}
+	report := &DoctorReport{}
+	for _, p := range probes {
+		detail, err := p.probe()
+		report.Results = append(report.Results, ProbeResult{Name: p.name, Err: err, Detail: detail, Required: required[p.name]})
+	}
+	for _, name := range cfg.requiredProbes {
+		known := false
+		for _, p := range probes {
+			known = known || p.name == name
+		}
+		if !known {
+			report.Results = append(report.Results, ProbeResult{Name: name, Err: fmt.Errorf("unknown probe %q", name), Required: true})
+		}
+	}
+
+	if l, ok := t.(logger); ok {
+		l.Logf("udp: doctor report:\n%s", report)
+	}
+	for _, res := range report.Results {
+		if res.Required && res.Err != nil {
+			t.Errorf("udp: required probe %s failed: %v", res.Name, res.Err)
+		}
+	}
+	return report
+}
+
+// loopbackPair returns a listener on an ephemeral loopback port of the given
+// network, and a socket connected to it.
+func loopbackPair(network string, ip net.IP) (*net.UDPConn, *net.UDPConn, error) {
+	server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := net.DialUDP(network, nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+	return server, client, nil
+}
+
+// echo sends payload from client and reads it back on server.
+func echo(server, client *net.UDPConn, payload []byte) error {
+	if _, err := client.Write(payload); err != nil {
+		return err
+	}
+	buf := make([]byte, maxDatagramSize)
+	server.SetReadDeadline(time.Now().Add(doctorTimeout))
+	n, _, err := server.ReadFromUDP(buf)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(buf[:n], payload) {
+		return fmt.Errorf("sent %d bytes but received %d", len(payload), n)
+	}
+	return nil
+}
+
+func probeBind() (string, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return "bound " + conn.LocalAddr().String(), nil
+}
+
+func probeLatency() (string, error) {
+	server, client, err := loopbackPair("udp4", net.IPv4(127, 0, 0, 1))
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+	defer client.Close()
+
+	const rounds = 100
+	latencies := make([]time.Duration, rounds)
+	for i := range latencies {
+		sent := time.Now()
+		if err := echo(server, client, []byte("ping")); err != nil {
+			return fmt.Sprintf("%d of %d packets delivered", i, rounds), err
+		}
+		latencies[i] = time.Since(sent)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return fmt.Sprintf("p50 %v, p99 %v, max %v over %d packets",
+		latencies[rounds/2], latencies[rounds*99/100], latencies[rounds-1], rounds), nil
+}
+
+func probeMaxDatagram() (string, error) {
+	server, client, err := loopbackPair("udp4", net.IPv4(127, 0, 0, 1))
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+	defer client.Close()
+
+	// The largest IPv4 UDP payload, then common MTU derived limits.
+	for _, size := range []int{65507, 32768, 16384, 9216, 8192, 1472, 508} {
+		if err := echo(server, client, make([]byte, size)); err == nil {
+			return fmt.Sprintf("%d bytes", size), nil
+		}
+	}
+	return "", fmt.Errorf("not even a 508 byte datagram was delivered")
+}
+
+func probeReadBuffer() (string, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	// Ask for far more than any system allows, to find the ceiling.
+	if err := conn.SetReadBuffer(1 << 30); err != nil {
+		return "", err
+	}
+	size, err := readBufferSize(conn)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SO_RCVBUF ceiling %d bytes", size), nil
+}
+
+func probeIPv6() (string, error) {
+	server, client, err := loopbackPair("udp6", net.IPv6loopback)
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+	defer client.Close()
+	if err := echo(server, client, []byte("ping")); err != nil {
+		return "", err
+	}
+	return "[::1] delivers", nil
+}
+
+func probeMulticast() (string, error) {
+	group := &net.UDPAddr{IP: net.IPv4(239, 255, 77, 77)}
+	server, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+	group.Port = server.LocalAddr().(*net.UDPAddr).Port
+	client, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	if err := echo(server, client, []byte("ping")); err != nil {
+		return "", err
+	}
+	return group.String() + " loops back", nil
+}
+
+func probeUnixgram() (string, error) {
+	dir, err := ioutil.TempDir("", "udpdoctor")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	addr := &net.UnixAddr{Name: filepath.Join(dir, "sock"), Net: "unixgram"}
+	server, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		return "", err
+	}
+	defer server.Close()
+	client, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("ping")); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 16)
+	server.SetReadDeadline(time.Now().Add(doctorTimeout))
+	if _, _, err := server.ReadFromUnix(buf); err != nil {
+		return "", err
+	}
+	return "delivers", nil
+}
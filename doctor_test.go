@@ -0,0 +1,40 @@
+package udp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	ft := &fakeT{}
+	report := Doctor(ft, WithRequiredProbes(ProbeLatency, "teleport"))
+	if len(report.Results) != 8 || report.Results[0].Name != ProbeBind || report.Results[0].Err != nil {
+		t.Errorf("Expected every probe to run, binding first, got %#v", report.Results)
+	}
+	unknown := report.Results[len(report.Results)-1]
+	if report.OK() || unknown.Name != "teleport" || !unknown.Required ||
+		len(ft.errors) != 1 || ft.errors[0] != `udp: required probe teleport failed: unknown probe "teleport"` {
+		t.Errorf("Expected only the unknown probe to fail, got %#v\n%s", ft.errors, report)
+	}
+	if len(ft.logs) != 1 || !strings.Contains(ft.logs[0], "latency      ok   p50 ") {
+		t.Errorf("Expected the report to be logged, got %#v", ft.logs)
+	}
+}
+
+func TestDoctorReport(t *testing.T) {
+	report := &DoctorReport{Results: []ProbeResult{
+		{Name: ProbeBind, Detail: "bound [::]:1234", Required: true},
+		{Name: ProbeIPv6, Err: errors.New("no ::1")},
+		{Name: ProbeMulticast, Err: errors.New("no route"), Required: true},
+	}}
+	want := "bind         ok   bound [::]:1234\n" +
+		"ipv6         warn  (no ::1)\n" +
+		"multicast    FAIL  (no route)\n"
+	if got := report.String(); got != want {
+		t.Errorf("Expected report %q, got %q", want, got)
+	}
+	if report.OK() {
+		t.Errorf("Expected a failed required probe to fail the report")
+	}
+}
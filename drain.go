@@ -0,0 +1,98 @@
+package udp
+
+import (
+	"time"
+)
+
+// Drain describes what a sender kept sending after the body returned.
+type Drain struct {
+	// Duration is the time from the body returning to the last packet, or 0
+	// if nothing was sent after the body.
+	Duration time.Duration
+	// Packets are the packets received after the body returned.
+	Packets []Packet
+}
+
+// ReceiveDrain captures what the given function sends, and keeps capturing
+// after it returns until the sender has been quiet for the idle timeout or
+// maxWait has passed, to see how long it takes the sender to drain.
+func (s *Server) ReceiveDrain(t TestingT, maxWait time.Duration, body fn, opts ...Option) *Drain {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	cfg.maxWait = maxWait
+	var bodyEnd time.Time
+	packets := c.capturePackets(t, func() {
+		body()
+		bodyEnd = time.Now()
+	}, cfg)
+
+	d := &Drain{}
+	for _, p := range packets {
+		if !p.Time.After(bodyEnd) {
+			continue
+		}
+		d.Packets = append(d.Packets, p)
+		if after := p.Time.Sub(bodyEnd); after > d.Duration {
+			d.Duration = after
+		}
+	}
+	return d
+}
+
+// ReceiveDrain calls Server.ReceiveDrain on the default server.
+func ReceiveDrain(t TestingT, maxWait time.Duration, body fn, opts ...Option) *Drain {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveDrain(t, maxWait, body, opts...)
+}
+
+// MeasureDrain returns how long after the given function returns the sender
+// keeps sending, waiting at most maxWait for it to go quiet.
+func (s *Server) MeasureDrain(t TestingT, maxWait time.Duration, body fn, opts ...Option) time.Duration {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return s.ReceiveDrain(t, maxWait, body, opts...).Duration
+}
+
+// MeasureDrain calls Server.MeasureDrain on the default server.
+func MeasureDrain(t TestingT, maxWait time.Duration, body fn, opts ...Option) time.Duration {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.MeasureDrain(t, maxWait, body, opts...)
+}
+
+// ShouldDrainWithin will fire a test error if the sender is still sending d
+// after the given function returns. The drain is returned either way, so that
+// it can be logged.
+func (s *Server) ShouldDrainWithin(t TestingT, d time.Duration, body fn, opts ...Option) *Drain {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	cfg := newCallConfig(opts)
+	// Wait one idle timeout past d, to see a packet that is just too late.
+	drain := s.ReceiveDrain(t, d+cfg.idleTimeout(s), body, opts...)
+	if drain.Duration > d {
+		c := s.newCall()
+		c.fatal = cfg.fatal
+		defer c.emitLog(t)
+		c.printLocation(t)
+		c.errorF("Expected the sender to go quiet within %v", d)
+		c.errorF("But it sent %d packets after the body, the last %v after", len(drain.Packets), drain.Duration)
+	}
+	return drain
+}
+
+// ShouldDrainWithin calls Server.ShouldDrainWithin on the default server.
+func ShouldDrainWithin(t TestingT, d time.Duration, body fn, opts ...Option) *Drain {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ShouldDrainWithin(t, d, body, opts...)
+}
@@ -0,0 +1,57 @@
+package udp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flushLater sends n packets, gap apart, after the body has returned.
+func flushLater(conn net.Conn, n int, gap time.Duration) fn {
+	return func() {
+		go func() {
+			for i := 0; i < n; i++ {
+				time.Sleep(gap)
+				conn.Write([]byte("flush"))
+			}
+		}()
+	}
+}
+
+func TestMeasureDrain(t *testing.T) {
+	udpClient := setup(t)
+
+	d := MeasureDrain(t, time.Second, flushLater(udpClient, 4, 10*time.Millisecond), WithTimeout(50*time.Millisecond))
+	if d < 30*time.Millisecond || d > 500*time.Millisecond {
+		t.Errorf("Expected the drain to take about 40ms, got %v", d)
+	}
+	if d := MeasureDrain(t, time.Second, func() {}); d != 0 {
+		t.Errorf("Expected no drain from a quiet body, got %v", d)
+	}
+}
+
+func TestMeasureDrainStopsAtMaxWait(t *testing.T) {
+	udpClient := setup(t)
+
+	started := time.Now()
+	MeasureDrain(t, 50*time.Millisecond, flushLater(udpClient, 100, 5*time.Millisecond), WithTimeout(50*time.Millisecond))
+	if elapsed := time.Since(started); elapsed > 300*time.Millisecond {
+		t.Errorf("Expected the capture to stop at maxWait, but it took %v", elapsed)
+	}
+}
+
+func TestShouldDrainWithin(t *testing.T) {
+	udpClient := setup(t)
+
+	drain := ShouldDrainWithin(t, time.Second, flushLater(udpClient, 2, 10*time.Millisecond), WithTimeout(50*time.Millisecond))
+	if len(drain.Packets) != 2 {
+		t.Errorf("Expected both flushed packets, got %#v", drain.Packets)
+	}
+
+	ft := &fakeT{}
+	ShouldDrainWithin(ft, 5*time.Millisecond, flushLater(udpClient, 2, 20*time.Millisecond), WithTimeout(50*time.Millisecond))
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected the sender to go quiet within 5ms") {
+		t.Errorf("Expected a late flush to fail, got %#v", ft.errors)
+	}
+}
@@ -0,0 +1,181 @@
+package udp
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+const (
+	defaultEntropyThreshold     = 0.9
+	defaultCompressionThreshold = 0.9
+	defaultMinPacketSize        = 32
+
+	// maxDumpSize bounds how much of a suspect packet is hex dumped.
+	maxDumpSize = 256
+)
+
+const randomnessCaveat = "This is a heuristic: encrypted data looks random, so it has close to the " +
+	"maximum byte entropy and does not compress. Passing does not prove a payload is encrypted."
+
+// WithEntropyThreshold sets the byte entropy, as a fraction of the maximum a
+// packet of its size can have, that separates random looking packets from
+// structured ones. It defaults to 0.9.
+func WithEntropyThreshold(fraction float64) Option {
+	return func(c *callConfig) {
+		c.entropyThreshold = fraction
+	}
+}
+
+// WithCompressionThreshold sets the compressed to original size ratio below
+// which a packet counts as compressible. It defaults to 0.9.
+func WithCompressionThreshold(ratio float64) Option {
+	return func(c *callConfig) {
+		c.compressionThreshold = ratio
+	}
+}
+
+// WithMinPacketSize skips packets smaller than n bytes in ShouldLookEncrypted
+// and ShouldLookLikeText, as short packets are too small to judge. It defaults
+// to 32.
+func WithMinPacketSize(n int) Option {
+	return func(c *callConfig) {
+		c.minPacketSize = n
+	}
+}
+
+// entropy returns the Shannon entropy of b's byte values, as a fraction of the
+// most a slice of its length can have.
+func entropy(b []byte) float64 {
+	if len(b) < 2 {
+		return 0
+	}
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	bits := 0.0
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(b))
+			bits -= p * math.Log2(p)
+		}
+	}
+	return bits / math.Log2(math.Min(float64(len(b)), 256))
+}
+
+// compressionRatio returns the size of b once deflated over its original size.
+func compressionRatio(b []byte) float64 {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write(b)
+	w.Close()
+	return float64(buf.Len()) / float64(len(b))
+}
+
+// randomnessFailures checks every large enough packet the given function
+// sends, describing those that don't look random when wantRandom, or that do
+// otherwise.
+func (c *call) randomnessFailures(t TestingT, body fn, wantRandom bool, opts []Option) []string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	cfg := newCallConfig(opts)
+	if cfg.entropyThreshold == 0 {
+		cfg.entropyThreshold = defaultEntropyThreshold
+	}
+	if cfg.compressionThreshold == 0 {
+		cfg.compressionThreshold = defaultCompressionThreshold
+	}
+	if cfg.minPacketSize == 0 {
+		cfg.minPacketSize = defaultMinPacketSize
+	}
+	packets := c.capturePackets(t, body, cfg)
+
+	checked := 0
+	failures := []string{}
+	for i, p := range packets {
+		if len(p.Payload) < cfg.minPacketSize {
+			continue
+		}
+		checked++
+		e, r := entropy(p.Payload), compressionRatio(p.Payload)
+		looksRandom := e >= cfg.entropyThreshold && r >= cfg.compressionThreshold
+		if looksRandom == wantRandom {
+			continue
+		}
+		if wantRandom {
+			failures = append(failures, fmt.Sprintf("Packet %d (%d bytes) doesn't look encrypted:", i, len(p.Payload)))
+		} else {
+			failures = append(failures, fmt.Sprintf("Packet %d (%d bytes) doesn't look like text:", i, len(p.Payload)))
+		}
+		failures = append(failures, fmt.Sprintf("Entropy %.2f (threshold %.2f), compresses to %.2f of its size (threshold %.2f)",
+			e, cfg.entropyThreshold, r, cfg.compressionThreshold))
+		dump := p.Payload
+		if len(dump) > maxDumpSize {
+			dump = dump[:maxDumpSize]
+		}
+		failures = append(failures, hex.Dump(dump))
+	}
+
+	if checked == 0 {
+		return []string{fmt.Sprintf("Expected packets of at least %d bytes to check, but got %d smaller packets", cfg.minPacketSize, len(packets))}
+	}
+	if len(failures) > 0 {
+		failures = append(failures, randomnessCaveat)
+	}
+	return failures
+}
+
+// ShouldLookEncrypted will fire a test error if any packet the given function
+// sends has low byte entropy or compresses well, both signs of an unencrypted
+// payload. Packets too small to judge are skipped, and it fails if none are
+// left.
+func (s *Server) ShouldLookEncrypted(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	if failures := c.randomnessFailures(t, body, true, opts); len(failures) > 0 {
+		c.printLocation(t)
+		for _, f := range failures {
+			c.errorF("%s", f)
+		}
+	}
+}
+
+// ShouldLookEncrypted calls Server.ShouldLookEncrypted on the default server.
+func ShouldLookEncrypted(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldLookEncrypted(t, body, opts...)
+}
+
+// ShouldLookLikeText will fire a test error if any packet the given function
+// sends looks encrypted: high byte entropy and no gain from compression.
+// Packets too small to judge are skipped, and it fails if none are left.
+func (s *Server) ShouldLookLikeText(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	if failures := c.randomnessFailures(t, body, false, opts); len(failures) > 0 {
+		c.printLocation(t)
+		for _, f := range failures {
+			c.errorF("%s", f)
+		}
+	}
+}
+
+// ShouldLookLikeText calls Server.ShouldLookLikeText on the default server.
+func ShouldLookLikeText(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldLookLikeText(t, body, opts...)
+}
@@ -0,0 +1,48 @@
+package udp
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestShouldLookEncrypted(t *testing.T) {
+	udpClient := setup(t)
+	random := make([]byte, 512)
+	rand.Read(random)
+	text := []byte(strings.Repeat("api.latency:12|ms|#env:prod\n", 10))
+
+	ShouldLookEncrypted(t, func() {
+		udpClient.Write(random)
+		udpClient.Write([]byte("short"))
+	})
+	ShouldLookLikeText(t, func() {
+		udpClient.Write(text)
+	})
+
+	ft := &fakeT{}
+	ShouldLookEncrypted(ft, func() {
+		udpClient.Write(random)
+		udpClient.Write(text)
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Packet 1 (280 bytes) doesn't look encrypted") ||
+		!strings.Contains(ft.errors[0], "heuristic") {
+		t.Errorf("Expected the text packet to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldLookLikeText(ft, func() {
+		udpClient.Write(random)
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "doesn't look like text") {
+		t.Errorf("Expected the random packet to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldLookEncrypted(ft, func() {
+		udpClient.Write([]byte("short"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "at least 32 bytes") {
+		t.Errorf("Expected a nothing to check error, got %#v", ft.errors)
+	}
+}
@@ -0,0 +1,88 @@
+package udp
+
+import (
+	"regexp"
+	"sort"
+)
+
+// RegexpExtractor returns an extract function for ShouldCoverEventTypes that
+// takes the event type from the given capture group of re's first match in a
+// packet. Packets re doesn't match have no event type.
+func RegexpExtractor(re *regexp.Regexp, group int) func([]byte) (string, bool) {
+	return func(payload []byte) (string, bool) {
+		m := re.FindSubmatch(payload)
+		if m == nil || group >= len(m) || m[group] == nil {
+			return "", false
+		}
+		return string(m[group]), true
+	}
+}
+
+// ShouldCoverEventTypes will fire a test error if the packets the given
+// function sends don't include every required event type, and a separate one
+// if any has an event type outside allowed, quoting a packet of each. extract
+// returns the event type of a packet, or false if it has none. A nil allowed
+// list allows any event type.
+func (s *Server) ShouldCoverEventTypes(t TestingT, extract func([]byte) (string, bool), required []string, allowed []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+
+	allowedSet := map[string]bool{}
+	for _, typ := range allowed {
+		allowedSet[typ] = true
+	}
+	seen := map[string]bool{}
+	unknown := []string{}
+	samples := map[string][]byte{}
+	for _, p := range packets {
+		typ, ok := extract(p.Payload)
+		if !ok {
+			continue
+		}
+		if allowed != nil && !allowedSet[typ] && samples[typ] == nil {
+			unknown = append(unknown, typ)
+			samples[typ] = p.Payload
+		}
+		seen[typ] = true
+	}
+
+	missing := []string{}
+	for _, typ := range required {
+		if !seen[typ] {
+			missing = append(missing, typ)
+		}
+	}
+	if len(missing) > 0 {
+		got := make([]string, 0, len(seen))
+		for typ := range seen {
+			got = append(got, typ)
+		}
+		sort.Strings(got)
+		c.printLocation(t)
+		c.errorF("Expected event types that were never received: %#v", missing)
+		c.errorF("But got event types: %#v", got)
+	}
+	// The two kinds of failure are reported as separate errors.
+	c.emitLog(t)
+
+	if len(unknown) > 0 {
+		c.printLocation(t)
+		c.errorF("Expected only allowed event types, but got:")
+		for _, typ := range unknown {
+			c.errorF("%#v, for example in %q", typ, samples[typ])
+		}
+	}
+}
+
+// ShouldCoverEventTypes calls Server.ShouldCoverEventTypes on the default
+// server.
+func ShouldCoverEventTypes(t TestingT, extract func([]byte) (string, bool), required []string, allowed []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldCoverEventTypes(t, extract, required, allowed, body, opts...)
+}
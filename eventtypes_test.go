@@ -0,0 +1,29 @@
+package udp
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShouldCoverEventTypes(t *testing.T) {
+	udpClient := setup(t)
+	extract := RegexpExtractor(regexp.MustCompile(`"type":"(\w+)"`), 1)
+	allowed := []string{"login", "logout", "purchase"}
+
+	ShouldCoverEventTypes(t, extract, []string{"login", "logout"}, allowed, func() {
+		udpClient.Write([]byte(`{"type":"login","user":1}`))
+		udpClient.Write([]byte(`{"type":"logout","user":1}`))
+		udpClient.Write([]byte(`not an event`))
+	})
+
+	ft := &fakeT{}
+	ShouldCoverEventTypes(ft, extract, []string{"login", "purchase"}, allowed, func() {
+		udpClient.Write([]byte(`{"type":"login","user":1}`))
+		udpClient.Write([]byte(`{"type":"refund","user":1}`))
+	})
+	if len(ft.errors) != 2 || !strings.Contains(ft.errors[0], `never received: []string{"purchase"}`) ||
+		!strings.Contains(ft.errors[1], `"refund", for example in "{\"type\":\"refund\",\"user\":1}"`) {
+		t.Errorf("Expected separate missing and unknown type errors, got %#v", ft.errors)
+	}
+}
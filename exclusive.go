@@ -0,0 +1,80 @@
+package udp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// tokenPrefix and tokenEnd frame the capture token a tagged Client puts
+	// in front of its payloads.
+	tokenPrefix = "\x00udp-token:"
+	tokenEnd    = "\x00"
+	tokenSize   = 16
+)
+
+// newToken returns a random capture token.
+func newToken() string {
+	b := make([]byte, tokenSize/2)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) captureToken() string {
+	s.sendsMu.Lock()
+	defer s.sendsMu.Unlock()
+	return s.token
+}
+
+// splitToken splits the capture token off a payload tagged by a Client made
+// WithCaptureToken. Untagged payloads are returned as they are.
+func splitToken(payload []byte) ([]byte, string) {
+	n := len(tokenPrefix) + tokenSize + len(tokenEnd)
+	if len(payload) < n || !bytes.HasPrefix(payload, []byte(tokenPrefix)) || string(payload[n-len(tokenEnd):n]) != tokenEnd {
+		return payload, ""
+	}
+	return payload[n:], string(payload[len(tokenPrefix) : n-len(tokenEnd)])
+}
+
+// isOwn reports whether p carries token, or was sent from one of ports.
+func isOwn(p Packet, token string, ports []int) bool {
+	if token != "" && p.token == token {
+		return true
+	}
+	for _, port := range ports {
+		if p.From != nil && p.From.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+// WithExclusiveUse fails the assertion if any packet it captures is not the
+// test's own, which happens when another test sends to the same port. A packet
+// is the test's own if it was sent by a Client made WithCaptureToken while
+// the capture was listening, or from one of allowedPorts, for senders that
+// don't use a Client. Foreign packets are listed with where they came from,
+// and are still seen by the assertion.
+func WithExclusiveUse(allowedPorts ...int) Option {
+	return func(c *callConfig) {
+		c.exclusiveUse = true
+		c.allowedPorts = allowedPorts
+	}
+}
+
+// reportForeign fails the assertion with the packets WithExclusiveUse found
+// not to be the test's own.
+func (c *call) reportForeign(t TestingT) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if len(c.foreign) == 0 {
+		return
+	}
+	c.printLocation(t)
+	for _, p := range c.foreign {
+		c.errorF("Foreign packet %#v from %v: another test may be sending to this address", string(p.Payload), p.From)
+	}
+	c.foreign = nil
+}
@@ -0,0 +1,45 @@
+package udp
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSplitToken(t *testing.T) {
+	token := newToken()
+	payload, got := splitToken([]byte(tokenPrefix + token + tokenEnd + "foo"))
+	if string(payload) != "foo" || got != token {
+		t.Errorf("Expected the token to be split off, got %q and %q", payload, got)
+	}
+	for _, untagged := range []string{"foo", "", tokenPrefix, tokenPrefix + token + "foo"} {
+		if payload, got := splitToken([]byte(untagged)); string(payload) != untagged || got != "" {
+			t.Errorf("Expected %q to be left as it is, got %q and %q", untagged, payload, got)
+		}
+	}
+}
+
+func TestWithExclusiveUse(t *testing.T) {
+	intruder := setup(t)
+	client := NewClient(t, WithCaptureToken())
+	defer client.Close()
+
+	ShouldReceiveOnly(t, "foo", func() {
+		client.Send("foo")
+	}, WithExclusiveUse())
+
+	ft := &fakeT{}
+	ShouldReceive(ft, "foo", func() {
+		client.Send("foo")
+		intruder.Write([]byte("bar"))
+	}, WithExclusiveUse())
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Foreign packet \"bar\" from ") ||
+		strings.Count(ft.errors[0], "At: ") != 1 {
+		t.Errorf("Expected the intruder's packet to be reported, got %#v", ft.errors)
+	}
+
+	ShouldReceiveOnly(t, "foobar", func() {
+		client.Send("foo")
+		intruder.Write([]byte("bar"))
+	}, WithExclusiveUse(intruder.LocalAddr().(*net.UDPAddr).Port))
+}
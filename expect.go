@@ -0,0 +1,127 @@
+package udp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AssertionError is the error the Expect functions return when an expectation
+// is not met.
+type AssertionError struct {
+	// Expected is what was expected: the string, or strings, given to the
+	// ExpectReceive functions. It is nil for Expect.
+	Expected interface{}
+	// Got is everything received, concatenated. It is empty for Expect.
+	Got string
+	// Message is the failure message, as the matching Should assertion
+	// reports it.
+	Message string
+}
+
+func (e *AssertionError) Error() string {
+	return e.Message
+}
+
+// errorCollector is a TestingT holding on to what an assertion reports, to be
+// returned as an error.
+type errorCollector struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (e *errorCollector) Errorf(format string, args ...interface{}) {
+	e.Error(fmt.Sprintf(format, args...))
+}
+
+func (e *errorCollector) Error(args ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, fmt.Sprint(args...))
+}
+
+func (e *errorCollector) Fatal(args ...interface{}) {
+	e.Error(args...)
+}
+
+// err returns what was reported as an *AssertionError, or nil if nothing was.
+func (e *errorCollector) err(expected interface{}, got string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.errors) == 0 {
+		return nil
+	}
+	return &AssertionError{Expected: expected, Got: got, Message: strings.Join(e.errors, "\n")}
+}
+
+// Expect runs assert, which makes assertions with the TestingT it is given,
+// and returns what they report as an *AssertionError, or nil if nothing. It
+// lets any assertion be used where there is no test to fail, such as an
+// integration harness not run by go test:
+//
+//	err := udp.Expect(func(t udp.TestingT) {
+//		udp.ShouldReceivePacket(t, "hits:1|c", flush)
+//	})
+//
+// An assertion failing with Fatal is not stopped, as it would be by
+// testing.T. Assertions in this package stop capturing after Fatal without
+// running the body, but may report further failures, which are all part of
+// the error returned.
+func Expect(assert func(t TestingT)) error {
+	e := &errorCollector{}
+	assert(e)
+	return e.err(nil, "")
+}
+
+// expectWith runs assert, a Should assertion, with an errorCollector, and
+// returns what it reports as an *AssertionError, with everything it received.
+func expectWith(expected interface{}, opts []Option, assert func(t TestingT, opts []Option)) error {
+	e := &errorCollector{}
+	got := ""
+	opts = append(append([]Option(nil), opts...), func(c *callConfig) {
+		c.received = func(packets []Packet) {
+			got = strings.Join(payloadStrings(packets), "")
+		}
+	})
+	assert(e, opts)
+	return e.err(expected, got)
+}
+
+// ExpectReceive is ShouldReceive returning an error, an *AssertionError,
+// instead of failing a test.
+func (s *Server) ExpectReceive(expected string, body fn, opts ...Option) error {
+	return expectWith(expected, opts, func(t TestingT, opts []Option) {
+		s.ShouldReceive(t, expected, body, opts...)
+	})
+}
+
+// ExpectReceive calls Server.ExpectReceive on the default server.
+func ExpectReceive(expected string, body fn, opts ...Option) error {
+	return defaultServer.ExpectReceive(expected, body, opts...)
+}
+
+// ExpectReceiveOnly is ShouldReceiveOnly returning an error, an
+// *AssertionError, instead of failing a test.
+func (s *Server) ExpectReceiveOnly(expected string, body fn, opts ...Option) error {
+	return expectWith(expected, opts, func(t TestingT, opts []Option) {
+		s.ShouldReceiveOnly(t, expected, body, opts...)
+	})
+}
+
+// ExpectReceiveOnly calls Server.ExpectReceiveOnly on the default server.
+func ExpectReceiveOnly(expected string, body fn, opts ...Option) error {
+	return defaultServer.ExpectReceiveOnly(expected, body, opts...)
+}
+
+// ExpectReceiveAll is ShouldReceiveAll returning an error, an
+// *AssertionError, instead of failing a test.
+func (s *Server) ExpectReceiveAll(expected []string, body fn, opts ...Option) error {
+	return expectWith(expected, opts, func(t TestingT, opts []Option) {
+		s.ShouldReceiveAll(t, expected, body, opts...)
+	})
+}
+
+// ExpectReceiveAll calls Server.ExpectReceiveAll on the default server.
+func ExpectReceiveAll(expected []string, body fn, opts ...Option) error {
+	return defaultServer.ExpectReceiveAll(expected, body, opts...)
+}
@@ -0,0 +1,72 @@
+package udp
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExpect(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	}
+
+	if err := ExpectReceiveOnly("foobar", send); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := ExpectReceiveAll([]string{"foo", "bar"}, send); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	err := ExpectReceiveAll([]string{"foo", "baz"}, send)
+	ae, ok := err.(*AssertionError)
+	if !ok || ae.Got != "foobar" || len(ae.Expected.([]string)) != 2 ||
+		!strings.HasSuffix(ae.Error(), "\nExpected to find: \"baz\"\nBut got: \"foobar\"") {
+		t.Errorf("Expected an AssertionError naming the missing string, got %#v", err)
+	}
+
+	err = ExpectReceive("foo", func() {})
+	if ae, ok := err.(*AssertionError); !ok || ae.Got != "" || !strings.HasSuffix(ae.Message, "But got: \"\"") {
+		t.Errorf("Expected an empty capture to be reported, got %#v", err)
+	}
+
+	err = Expect(func(t TestingT) {
+		ShouldReceivePacket(t, "baz", send)
+	})
+	if err == nil || !strings.Contains(err.Error(), "Expected a packet: \"baz\"") {
+		t.Errorf("Expected the assertion's failure as an error, got %v", err)
+	}
+	if err := Expect(func(t TestingT) { ShouldReceivePacket(t, "foo", send) }); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestExpectMatchesShould(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("foobar"))
+	}
+	location := regexp.MustCompile(`At: \S+`)
+
+	tests := []struct {
+		expect func() error
+		should func(t TestingT)
+	}{
+		{func() error { return ExpectReceive("baz", send) }, func(t TestingT) { ShouldReceive(t, "baz", send) }},
+		{func() error { return ExpectReceiveOnly("foo", send) }, func(t TestingT) { ShouldReceiveOnly(t, "foo", send) }},
+		{func() error { return ExpectReceiveAll([]string{"foo", "baz"}, send) }, func(t TestingT) {
+			ShouldReceiveAll(t, []string{"foo", "baz"}, send)
+		}},
+	}
+	for i, test := range tests {
+		err := test.expect()
+		ft := &fakeT{}
+		test.should(ft)
+		if err == nil || len(ft.errors) != 1 ||
+			location.ReplaceAllString(err.Error(), "At:") != location.ReplaceAllString(ft.errors[0], "At:") {
+			t.Errorf("%d: expected the Should assertion's message, got %v, want %#v", i, err, ft.errors)
+		}
+	}
+}
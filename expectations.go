@@ -0,0 +1,216 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+)
+
+// expectationKinds makes a Matcher from the value of each kind of entry
+// an expectation file can hold.
+var expectationKinds = map[string]func(raw json.RawMessage) (Matcher, error){
+	"contains": func(raw json.RawMessage) (Matcher, error) {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return Contains(s), err
+	},
+	"notContains": func(raw json.RawMessage) (Matcher, error) {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return NotContains(s), err
+	},
+	"regex": func(raw json.RawMessage) (Matcher, error) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		return Matches(re), nil
+	},
+	"packetCount": func(raw json.RawMessage) (Matcher, error) {
+		var n int
+		err := json.Unmarshal(raw, &n)
+		return PacketCount(n), err
+	},
+	"jsonSubset": func(raw json.RawMessage) (Matcher, error) {
+		var v interface{}
+		err := json.Unmarshal(raw, &v)
+		return JSONSubset(v), err
+	},
+}
+
+// fileMatcher is an entry of an expectation file, which names where it came
+// from when it fails.
+type fileMatcher struct {
+	Matcher
+	pos  string
+	name string
+}
+
+func (m *fileMatcher) Match(packets []string) error {
+	err := m.Matcher.Match(packets)
+	if err == nil {
+		return nil
+	}
+	if m.name == "" {
+		return fmt.Errorf("%s: %v", m.pos, err)
+	}
+	return fmt.Errorf("%s: %s: %v", m.pos, m.name, err)
+}
+
+// LoadExpectations reads the PacketMatchers in the JSON expectation file at
+// path. The file holds an array of entries, each an object with exactly one of
+// these keys, and optionally a "name":
+//
+//	[
+//		{"name": "hits counter", "contains": "hits:1|c"},
+//		{"notContains": "error"},
+//		{"regex": "latency:[0-9.]+\\|ms"},
+//		{"packetCount": 2},
+//		{"jsonSubset": {"level": "info"}}
+//	]
+//
+// contains, notContains and regex check everything received, concatenated;
+// packetCount the number of datagrams; and jsonSubset that a datagram is JSON
+// holding the given fields. An invalid entry is reported with the file name
+// and the line it starts on, as are the failures of the PacketMatchers
+// returned.
+func LoadExpectations(path string) ([]Matcher, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineAt(data, serr.Offset), err)
+		}
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	lines := entryLines(data)
+	matchers := make([]Matcher, len(entries))
+	for i, raw := range entries {
+		pos := fmt.Sprintf("%s:%d", path, lines[i])
+		m, err := parseExpectation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %v", pos, i, err)
+		}
+		m.pos = pos
+		matchers[i] = m
+	}
+	return matchers, nil
+}
+
+// parseExpectation parses one entry of an expectation file.
+func parseExpectation(raw json.RawMessage) (*fileMatcher, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("expected an object: %v", err)
+	}
+	m := &fileMatcher{}
+	if name, ok := fields["name"]; ok {
+		if err := json.Unmarshal(name, &m.name); err != nil {
+			return nil, fmt.Errorf("name: %v", err)
+		}
+		delete(fields, "name")
+	}
+	kinds := []string{}
+	for k := range fields {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	if len(kinds) != 1 {
+		return nil, fmt.Errorf("expected exactly one of contains, notContains, regex, packetCount or jsonSubset, but got %q", kinds)
+	}
+	kind := expectationKinds[kinds[0]]
+	if kind == nil {
+		return nil, fmt.Errorf("unknown key %q", kinds[0])
+	}
+	matcher, err := kind(fields[kinds[0]])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", kinds[0], err)
+	}
+	m.Matcher = matcher
+	return m, nil
+}
+
+// lineAt returns the line the given byte offset of data is on.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// entryLines returns the line each element of the JSON array in data starts
+// on. It expects data to be valid JSON.
+func entryLines(data []byte) []int {
+	lines := []int{}
+	line, depth := 1, 0
+	inString, escaped, expect := false, false, false
+	for _, b := range data {
+		if b == '\n' {
+			line++
+		}
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			continue
+		}
+		if depth == 1 && expect && b != ']' {
+			lines = append(lines, line)
+			expect = false
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '[', '{':
+			depth++
+			expect = depth == 1
+		case ']', '}':
+			depth--
+		case ',':
+			expect = depth == 1
+		}
+	}
+	return lines
+}
+
+// ShouldSatisfyFile will fire a test error unless what the given function
+// sends over UDP satisfies every entry of the expectation file at path, read
+// with LoadExpectations. Each failing entry is reported with its name and
+// line. A file that can't be loaded fails the test without running the body.
+func (s *Server) ShouldSatisfyFile(t TestingT, path string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	matchers, err := LoadExpectations(path)
+	if err != nil {
+		t.Fatal(fmt.Sprintf("udp: invalid expectations at %s: %v", callerLocation(), err))
+		return
+	}
+	s.ShouldSatisfy(t, AllOf(matchers...), body, opts...)
+}
+
+// ShouldSatisfyFile calls Server.ShouldSatisfyFile on the default server.
+func ShouldSatisfyFile(t TestingT, path string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldSatisfyFile(t, path, body, opts...)
+}
@@ -0,0 +1,83 @@
+package udp
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// writeExpectations writes content to a temporary expectation file and
+// returns its path.
+func writeExpectations(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "expectations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestShouldSatisfyFile(t *testing.T) {
+	udpClient := setup(t)
+	path := writeExpectations(t, `[
+	{"name": "hits counter", "contains": "hits:1|c"},
+	{"notContains": "error"},
+	{"regex": "latency:[0-9.]+\\|ms"},
+	{"packetCount": 2},
+	{"name": "json", "jsonSubset": {"level": "info", "tags": ["a"]}}
+]`)
+	defer os.Remove(path)
+
+	ShouldSatisfyFile(t, path, func() {
+		udpClient.Write([]byte("hits:1|c\nlatency:12|ms"))
+		udpClient.Write([]byte(`{"level":"info","tags":["a"],"msg":"hi"}`))
+	})
+
+	ft := &fakeT{}
+	ShouldSatisfyFile(ft, path, func() {
+		udpClient.Write([]byte("hits:2|c error"))
+	})
+	if len(ft.errors) != 1 {
+		t.Fatalf("Expected one error, got %#v", ft.errors)
+	}
+	for _, expected := range []string{
+		path + `:2: hits counter: Expected to find: "hits:1|c"`,
+		path + `:3: Expected not to find: "error"`,
+		path + `:4: Expected to match: "latency:[0-9.]+\\|ms"`,
+		path + `:5: Expected exactly 2 packets, but got 1`,
+		path + `:6: json: Expected a JSON packet holding: {"level":"info","tags":["a"]}`,
+		`But got packets: []string{"hits:2|c error"}`,
+	} {
+		if !strings.Contains(ft.errors[0], expected) {
+			t.Errorf("Expected %q to be reported, got %s", expected, ft.errors[0])
+		}
+	}
+}
+
+func TestLoadExpectationsErrors(t *testing.T) {
+	for content, expected := range map[string]string{
+		"[\n{\"contains\": \"a\"},\n{\"contains\": \"a\", \"regex\": \"b\"}\n]": `:3: entry 1: expected exactly one of contains, notContains, regex, packetCount or jsonSubset, but got ["contains" "regex"]`,
+		"[\n\n  {\"name\": \"x\", \"equals\": \"a\"}]":                          `:3: entry 0: unknown key "equals"`,
+		"[{\"regex\": \"(\"}]":         `:1: entry 0: regex: error parsing regexp`,
+		"[{\"packetCount\": \"two\"}]": `:1: entry 0: packetCount: json: cannot unmarshal string`,
+		"[\n{\"contains\": \"a\"\n]":   `:3: invalid character ']'`,
+	} {
+		path := writeExpectations(t, content)
+		defer os.Remove(path)
+		_, err := LoadExpectations(path)
+		if err == nil || !strings.HasPrefix(err.Error(), path) || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %q, got %v", expected, content, err)
+		}
+	}
+
+	ft := &fakeT{}
+	ran := false
+	ShouldSatisfyFile(ft, "does-not-exist.json", func() { ran = true })
+	if ran || len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "udp: invalid expectations at ") {
+		t.Errorf("Expected a missing file to be fatal, got %#v", ft.fatals)
+	}
+}
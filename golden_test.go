@@ -0,0 +1,75 @@
+package udp
+
+import (
+	"regexp"
+	"testing"
+)
+
+// The package level assertions are the original API of this package. Their
+// failure messages are checked in full so that changes behind them can't
+// alter what existing suites print.
+func TestGoldenFailureMessages(t *testing.T) {
+	udpClient := setup(t)
+	send := func(payloads ...string) fn {
+		return func() {
+			for _, p := range payloads {
+				udpClient.Write([]byte(p))
+			}
+		}
+	}
+	location := regexp.MustCompile(`At: \S+golden_test\.go:\d+`)
+	// The listener's address depends on whether the host has IPv6.
+	readErr := regexp.MustCompile(`read udp \S+:\d+`)
+
+	tests := []struct {
+		name   string
+		assert func(t TestingT)
+		golden string
+	}{
+		{"ShouldReceiveOnly", func(t TestingT) {
+			ShouldReceiveOnly(t, "foo", send("foo", "bar"))
+		}, "At: <location>\nExpected: \"foo\"\nBut got: \"foobar\""},
+		{"ShouldReceiveOnly without data", func(t TestingT) {
+			ShouldReceiveOnly(t, "foo", send())
+		}, "Error reading udp data: read udp <addr>: i/o timeout\nAt: <location>\nExpected: \"foo\"\nBut got: \"\""},
+		{"ShouldNotReceiveOnly", func(t TestingT) {
+			ShouldNotReceiveOnly(t, "foo", send("foo"))
+		}, "At: <location>\nExpected not to get: \"foo\""},
+		{"ShouldReceiveSomethingButNot", func(t TestingT) {
+			ShouldReceiveSomethingButNot(t, "foo", send())
+		}, "At: <location>\nExpected some data other than: \"foo\"\nBut got no data (ShouldNotReceiveOnly would have passed)"},
+		{"ShouldReceive", func(t TestingT) {
+			ShouldReceive(t, "foo", send("bar"))
+		}, "At: <location>\nExpected: \"foo\"\nBut got: \"bar\""},
+		{"ShouldNotReceive", func(t TestingT) {
+			ShouldNotReceive(t, "foo", send("foobar"))
+		}, "At: <location>\nExpected not to find: \"foo\"\nBut got: \"foobar\""},
+		{"ShouldReceiveNothing", func(t TestingT) {
+			ShouldReceiveNothing(t, send("foo"))
+		}, "At: <location>\nExpected no data, but got: \"foo\""},
+		{"ShouldReceiveAll", func(t TestingT) {
+			ShouldReceiveAll(t, []string{"foo", "bar", "baz"}, send("foo"))
+		}, "At: <location>\nExpected to find: \"bar\"\nExpected to find: \"baz\"\nBut got: \"foo\""},
+		{"ShouldNotReceiveAny", func(t TestingT) {
+			ShouldNotReceiveAny(t, []string{"foo", "bar", "baz"}, send("foo", "baz"))
+		}, "At: <location>\nExpected not to find: \"foo\"\nExpected not to find: \"baz\"\nBut got: \"foobaz\""},
+		{"ShouldReceiveAllAndNotReceiveAny", func(t TestingT) {
+			ShouldReceiveAllAndNotReceiveAny(t, []string{"foo", "bar"}, []string{"baz"}, send("foo", "baz"))
+		}, "At: <location>\nExpected to find: \"bar\"\nExpected not to find: \"baz\"\nbut got: \"foobaz\""},
+		{"ReceiveString", func(t TestingT) {
+			ReceiveString(t, send())
+		}, "Error reading udp data: read udp <addr>: i/o timeout"},
+	}
+	for _, test := range tests {
+		ft := &fakeT{}
+		test.assert(ft)
+		if len(ft.errors) != 1 {
+			t.Errorf("%s: expected one error, got %#v", test.name, ft.errors)
+			continue
+		}
+		got := location.ReplaceAllString(ft.errors[0], "At: <location>")
+		if got = readErr.ReplaceAllString(got, "read udp <addr>"); got != test.golden {
+			t.Errorf("%s: expected the message\n%s\nbut got\n%s", test.name, test.golden, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"bytes"
+)
+
+// WithGenesis makes ShouldFormHashChain require the first packet of the chain
+// to embed a previous hash of all zeros.
+func WithGenesis() Option {
+	return func(c *callConfig) {
+		c.genesis = true
+	}
+}
+
+// WithChainFilter makes ShouldFormHashChain skip the packets for which
+// inChain returns false, for ports that carry other traffic too.
+func WithChainFilter(inChain func(payload []byte) bool) Option {
+	return func(c *callConfig) {
+		c.chainFilter = inChain
+	}
+}
+
+// ShouldFormHashChain will fire a test error unless every packet the given
+// function sends embeds the hash of the packet before it: extractPrev(payload)
+// of each packet must equal hashOf(payload) of the previous one. Only the
+// first broken link is reported.
+func (s *Server) ShouldFormHashChain(t TestingT, extractPrev func([]byte) []byte, hashOf func([]byte) []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	packets := c.capturePackets(t, body, cfg)
+
+	// chain holds the index in packets of each packet in the chain.
+	chain := []int{}
+	for i, p := range packets {
+		if cfg.chainFilter == nil || cfg.chainFilter(p.Payload) {
+			chain = append(chain, i)
+		}
+	}
+	if len(chain) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected a hash chain, but got no packets in it out of %d", len(packets))
+		return
+	}
+
+	if cfg.genesis {
+		first := chain[0]
+		if prev := extractPrev(packets[first].Payload); len(prev) == 0 || !allZero(prev) {
+			c.printLocation(t)
+			c.errorF("Expected packet %d to start the chain with a zero previous hash", first)
+			c.errorF("But it embeds: %x", prev)
+			return
+		}
+	}
+	for k := 1; k < len(chain); k++ {
+		i, j := chain[k-1], chain[k]
+		want := hashOf(packets[i].Payload)
+		if got := extractPrev(packets[j].Payload); !bytes.Equal(got, want) {
+			c.printLocation(t)
+			c.errorF("Broken hash chain between packets %d and %d", i, j)
+			c.errorF("Expected packet %d to embed: %x", j, want)
+			c.errorF("But it embeds: %x", got)
+			return
+		}
+	}
+}
+
+// ShouldFormHashChain calls Server.ShouldFormHashChain on the default server.
+func ShouldFormHashChain(t TestingT, extractPrev func([]byte) []byte, hashOf func([]byte) []byte, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldFormHashChain(t, extractPrev, hashOf, body, opts...)
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
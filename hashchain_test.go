@@ -0,0 +1,59 @@
+package udp
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func sha(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// prevHash reads the previous hash from the first 32 bytes of a payload.
+func prevHash(b []byte) []byte {
+	if len(b) < sha256.Size {
+		return nil
+	}
+	return b[:sha256.Size]
+}
+
+// chain returns n payloads, each led by the hash of the one before.
+func chain(n int) [][]byte {
+	payloads := make([][]byte, n)
+	prev := make([]byte, sha256.Size)
+	for i := range payloads {
+		payloads[i] = append(append([]byte(nil), prev...), byte('a'+i))
+		prev = sha(payloads[i])
+	}
+	return payloads
+}
+
+func TestShouldFormHashChain(t *testing.T) {
+	udpClient := setup(t)
+	payloads := chain(4)
+
+	ShouldFormHashChain(t, prevHash, sha, func() {
+		for i, p := range payloads {
+			udpClient.Write(p)
+			if i == 1 {
+				udpClient.Write([]byte("noise"))
+			}
+		}
+	}, WithGenesis(), WithChainFilter(func(b []byte) bool { return len(b) > sha256.Size }))
+
+	ft := &fakeT{}
+	ShouldFormHashChain(ft, prevHash, sha, func() {
+		udpClient.Write(payloads[0])
+		udpClient.Write(payloads[2])
+	})
+	ShouldFormHashChain(ft, prevHash, sha, func() {
+		udpClient.Write(payloads[1])
+	}, WithGenesis())
+	if len(ft.errors) != 2 ||
+		!strings.Contains(ft.errors[0], "Broken hash chain between packets 0 and 1") ||
+		!strings.Contains(ft.errors[1], "Expected packet 0 to start the chain with a zero previous hash") {
+		t.Errorf("Expected the broken link and the bad genesis to be reported, got %#v", ft.errors)
+	}
+}
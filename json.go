@@ -0,0 +1,104 @@
+package udp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ShouldReceiveJSON will fire a test error unless what the given function sends
+// over UDP is JSON equal to expectedJSON: the same values, whatever the key
+// order and spacing. A malformed expectedJSON fails the test without running
+// the body.
+func (s *Server) ShouldReceiveJSON(t TestingT, expectedJSON string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	var expected interface{}
+	if err := json.Unmarshal([]byte(expectedJSON), &expected); err != nil {
+		t.Fatal(fmt.Sprintf("udp: invalid expected JSON at %s: %v: %#v", callerLocation(), err, expectedJSON))
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	raw := c.captureMessage(t, body, true, newCallConfig(opts))
+	var got interface{}
+	if err := json.Unmarshal([]byte(raw), &got); err != nil {
+		c.printLocation(t)
+		c.errorF("Expected JSON: %s", expectedJSON)
+		c.errorF("But got malformed JSON (%v): %#v", err, raw)
+		return
+	}
+	if !reflect.DeepEqual(got, expected) {
+		c.printLocation(t)
+		c.errorF("Expected JSON: %s", expectedJSON)
+		c.errorF("But got: %s", raw)
+	}
+}
+
+// ShouldReceiveJSON calls Server.ShouldReceiveJSON on the default server.
+func ShouldReceiveJSON(t TestingT, expectedJSON string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveJSON(t, expectedJSON, body, opts...)
+}
+
+// normalizeJSON returns v as it would be decoded from JSON once encoded, so
+// that an int compares equal to the float64 a decoded number is.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// ShouldReceiveContainsJSONField will fire a test error unless what the given
+// function sends over UDP is a JSON object whose key field holds value. Value
+// is compared as it would be decoded from JSON once encoded, so 3 matches the
+// number 3, and a struct the object it encodes to. A value that can't be
+// encoded fails the test without running the body.
+func (s *Server) ShouldReceiveContainsJSONField(t TestingT, key string, value interface{}, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	want, err := normalizeJSON(value)
+	if err != nil {
+		t.Fatal(fmt.Sprintf("udp: invalid expected JSON value at %s: %v: %#v", callerLocation(), err, value))
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	raw := c.captureMessage(t, body, true, newCallConfig(opts))
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &got); err != nil {
+		c.printLocation(t)
+		c.errorF("Expected a JSON object with %#v: %#v", key, value)
+		c.errorF("But got malformed JSON (%v): %#v", err, raw)
+		return
+	}
+	field, ok := got[key]
+	if !ok {
+		c.printLocation(t)
+		c.errorF("Expected a JSON object with %#v: %#v", key, value)
+		c.errorF("But it has no such key: %s", raw)
+		return
+	}
+	if !reflect.DeepEqual(field, want) {
+		c.printLocation(t)
+		c.errorF("Expected a JSON object with %#v: %#v", key, value)
+		c.errorF("But it holds %#v: %s", field, raw)
+	}
+}
+
+// ShouldReceiveContainsJSONField calls Server.ShouldReceiveContainsJSONField on
+// the default server.
+func ShouldReceiveContainsJSONField(t TestingT, key string, value interface{}, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveContainsJSONField(t, key, value, body, opts...)
+}
@@ -0,0 +1,59 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShouldReceiveJSON(t *testing.T) {
+	udpClient := setup(t)
+	send := func(s string) fn {
+		return func() {
+			udpClient.Write([]byte(s))
+		}
+	}
+
+	ShouldReceiveJSON(t, `{"a": 1, "b": [true, null]}`, send(`{"b":[true,null],"a":1}`))
+
+	ft := &fakeT{}
+	ShouldReceiveJSON(ft, `{"a": 1}`, send(`{"a":2}`))
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected JSON: {\"a\": 1}\nBut got: {\"a\":2}") {
+		t.Errorf("Expected the mismatch to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldReceiveJSON(ft, `{"a": 1}`, send(`{"a":`))
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "But got malformed JSON (unexpected end of JSON input): \"{\\\"a\\\":\"") {
+		t.Errorf("Expected malformed JSON to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ran := false
+	ShouldReceiveJSON(ft, `{a}`, func() { ran = true })
+	if ran || len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "udp: invalid expected JSON at ") ||
+		!strings.HasSuffix(ft.fatals[0], "\"{a}\"") {
+		t.Errorf("Expected malformed expected JSON to be fatal, got %#v", ft.fatals)
+	}
+}
+
+func TestShouldReceiveContainsJSONField(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte(`{"level":"info","count":3}`))
+	}
+
+	ShouldReceiveContainsJSONField(t, "level", "info", send)
+	ShouldReceiveContainsJSONField(t, "count", 3.0, send)
+	ShouldReceiveContainsJSONField(t, "count", 3, send)
+
+	ft := &fakeT{}
+	ShouldReceiveContainsJSONField(ft, "level", "error", send)
+	ShouldReceiveContainsJSONField(ft, "msg", "hi", send)
+	ShouldReceiveContainsJSONField(ft, "level", "info", func() {
+		udpClient.Write([]byte(`level=info`))
+	})
+	if len(ft.errors) != 3 || !strings.Contains(ft.errors[0], "But it holds \"info\"") ||
+		!strings.Contains(ft.errors[1], "But it has no such key") || !strings.Contains(ft.errors[2], "But got malformed JSON") {
+		t.Errorf("Expected each failure to be reported, got %#v", ft.errors)
+	}
+}
@@ -0,0 +1,109 @@
+package udp
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// A Ledger keeps every packet captured by the assertions it is passed to with
+// WithLedger, so that a rule spanning several captures, such as a side effect
+// happening only once across retries, can be checked afterwards.
+type Ledger struct {
+	mu      sync.Mutex
+	windows int
+	entries []ledgerEntry
+}
+
+type ledgerEntry struct {
+	hash    [sha256.Size]byte
+	payload []byte
+	at      time.Time
+	window  int
+	caller  string
+}
+
+// NewLedger returns an empty Ledger.
+func NewLedger() *Ledger {
+	return &Ledger{}
+}
+
+// WithLedger records every packet the assertion captures in led.
+func WithLedger(led *Ledger) Option {
+	return func(c *callConfig) {
+		c.ledger = led
+	}
+}
+
+func (l *Ledger) record(caller string, packets []Packet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.windows++
+	for _, p := range packets {
+		l.entries = append(l.entries, ledgerEntry{sha256.Sum256(p.Payload), p.Payload, p.Time, l.windows, caller})
+	}
+}
+
+// matching returns the entries whose payload matches pattern, along with the
+// number of windows recorded, both read at once. It returns false, having
+// failed the test, if pattern is invalid.
+func (l *Ledger) matching(t TestingT, pattern string) ([]ledgerEntry, int, bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatal(err)
+		return nil, 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var matched []ledgerEntry
+	for _, e := range l.entries {
+		if re.Match(e.payload) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, l.windows, true
+}
+
+func (l *Ledger) reportOccurrences(c *call, entries []ledgerEntry) {
+	for _, e := range entries {
+		c.errorF("  window %d at %s, %s: %q (sha256 %x)", e.window, e.caller,
+			e.at.Format("15:04:05.000000"), e.payload, e.hash[:4])
+	}
+}
+
+// AssertExactlyOnce will fire a test error if, across every capture recorded
+// in the ledger, the number of packets matching the given regular expression
+// is not exactly one.
+func (l *Ledger) AssertExactlyOnce(t TestingT, pattern string) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := &call{}
+	defer c.emitLog(t)
+	matched, windows, ok := l.matching(t, pattern)
+	if ok && len(matched) != 1 {
+		c.printLocation(t)
+		c.errorF("Expected %q to be received exactly once across %d windows, but it was received %d times", pattern, windows, len(matched))
+		l.reportOccurrences(c, matched)
+	}
+}
+
+// AssertAtMostOnce will fire a test error if, across every capture recorded in
+// the ledger, more than one packet matches the given regular expression.
+func (l *Ledger) AssertAtMostOnce(t TestingT, pattern string) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := &call{}
+	defer c.emitLog(t)
+	matched, windows, ok := l.matching(t, pattern)
+	if ok && len(matched) > 1 {
+		c.printLocation(t)
+		c.errorF("Expected %q to be received at most once across %d windows, but it was received %d times", pattern, windows, len(matched))
+		l.reportOccurrences(c, matched)
+	}
+}
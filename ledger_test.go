@@ -0,0 +1,38 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLedger(t *testing.T) {
+	udpClient := setup(t)
+	led := NewLedger()
+
+	for i := 0; i < 3; i++ {
+		ShouldReceiveAllAndNotReceiveAny(t, []string{"retry"}, nil, func() {
+			udpClient.Write([]byte("retry"))
+			if i == 1 {
+				udpClient.Write([]byte("side.effect:1|c"))
+			}
+		}, WithLedger(led))
+	}
+
+	led.AssertExactlyOnce(t, `^side\.effect:`)
+	led.AssertAtMostOnce(t, `^side\.effect:`)
+	led.AssertAtMostOnce(t, `^missing`)
+
+	ft := &fakeT{}
+	led.AssertExactlyOnce(ft, `^retry$`)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "received 3 times") ||
+		!strings.Contains(ft.errors[0], "window 3 at ") || !strings.Contains(ft.errors[0], "ledger_test.go:") {
+		t.Errorf("Expected every occurrence to be attributed, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	led.AssertAtMostOnce(ft, `(`)
+	led.AssertExactlyOnce(ft, `(`)
+	if len(ft.fatals) != 2 || len(ft.errors) != 0 {
+		t.Errorf("Expected an invalid pattern to be fatal and nothing else, got %#v %#v", ft.fatals, ft.errors)
+	}
+}
@@ -0,0 +1,35 @@
+package udp
+
+import "net"
+
+// Listener is a Server whose socket is connected to a single peer. It has
+// every assertion a Server has, each only seeing packets from that peer.
+type Listener struct {
+	*Server
+}
+
+// NewConnectedListener binds localAddr and connects the socket to remoteAddr,
+// so only packets sent from remoteAddr are ever read from it. This is the right
+// model for one-to-one UDP protocols, where accepting packets from any other
+// sender would be a bug.
+func NewConnectedListener(t TestingT, localAddr, remoteAddr string) *Listener {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	laddr, err := net.ResolveUDPAddr("udp", localAddr)
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	raddr, err := net.ResolveUDPAddr("udp", remoteAddr)
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	conn, err := net.DialUDP("udp", laddr, raddr)
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	return &Listener{&Server{addr: &localAddr, conn: conn}}
+}
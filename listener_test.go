@@ -0,0 +1,138 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnectedListener(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	stranger, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+
+	l := NewConnectedListener(t, "127.0.0.1:0", peer.LocalAddr().String())
+	defer l.Close()
+	to, err := net.ResolveUDPAddr("udp", l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := l.ReceiveString(t, func() {
+		stranger.WriteTo([]byte("bar"), to)
+		peer.WriteTo([]byte("foo"), to)
+	})
+	if got != "foo" {
+		t.Errorf("Expected only the connected peer's data, got %#v", got)
+	}
+
+	l.ShouldReceiveAllAndNotReceiveAny(t, []string{"foo"}, []string{"bar"}, func() {
+		stranger.WriteTo([]byte("bar"), to)
+		peer.WriteTo([]byte("foo"), to)
+	})
+}
+
+func TestListenUntil(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	l := NewConnectedListener(t, "127.0.0.1:0", peer.LocalAddr().String())
+	defer l.Close()
+	to, err := net.ResolveUDPAddr("udp", l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	packets := l.ListenUntil(t, context.Background(), 100*time.Millisecond, func() {
+		go func() {
+			for i := 0; i < 3; i++ {
+				time.Sleep(20 * time.Millisecond)
+				peer.WriteTo([]byte("tick"), to)
+			}
+		}()
+	})
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected to listen for the whole duration, but stopped after %v", elapsed)
+	}
+	if len(packets) != 3 {
+		t.Errorf("Expected the packets sent across the duration, got %d", len(packets))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	started = time.Now()
+	l.ListenUntil(t, ctx, time.Minute, func() {})
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected a done context to stop listening, but it took %v", elapsed)
+	}
+}
+
+func TestListenN(t *testing.T) {
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	l := NewConnectedListener(t, "127.0.0.1:0", peer.LocalAddr().String())
+	defer l.Close()
+	to, err := net.ResolveUDPAddr("udp", l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	packets := l.ListenN(t, ctx, 2, func() {
+		go func() {
+			for _, p := range []string{"one", "two", "three"} {
+				time.Sleep(10 * time.Millisecond)
+				peer.WriteTo([]byte(p), to)
+			}
+		}()
+	})
+	if len(packets) != 2 || string(packets[1].Payload) != "two" {
+		t.Errorf("Expected the first two packets, got %#v", packets)
+	}
+	l.ShouldReceiveOnly(t, "three", func() {}, WithTimeout(100*time.Millisecond))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if packets := l.ListenN(t, ctx, 1, func() {}); len(packets) != 0 {
+		t.Errorf("Expected no packets once the context is done, got %#v", packets)
+	}
+}
+
+func TestListenNWithoutDeadline(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := s.NewClient(t)
+	defer client.Close()
+
+	started := time.Now()
+	packets := s.ListenN(t, nil, 2, func() {
+		client.Send("one")
+	})
+	if len(packets) != 1 || time.Since(started) > time.Second {
+		t.Errorf("Expected a nil context to wait only for Timeout, got %#v after %v", packets, time.Since(started))
+	}
+	packets = s.ListenN(t, context.Background(), 0, func() {
+		client.Send("two")
+	})
+	if packets != nil {
+		t.Errorf("Expected nothing for n = 0, got %#v", packets)
+	}
+}
@@ -0,0 +1,120 @@
+package udp
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// captureMarked returns the packets the given function sends and when it
+// called mark, which is zero if it never did. The body calls mark at some
+// point, such as when it flips a feature flag, to split the packets it sends
+// into before and after. Only the first call counts.
+func (c *call) captureMarked(t TestingT, body func(mark func()), opts []Option) ([]Packet, time.Time) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	var once sync.Once
+	var marked time.Time
+	packets := c.capturePackets(t, func() {
+		body(func() {
+			once.Do(func() { marked = time.Now() })
+		})
+	}, newCallConfig(opts))
+	return packets, marked
+}
+
+// reportAroundMark reports every packet containing substr, with how long
+// before or after the mark it arrived.
+func (c *call) reportAroundMark(packets []Packet, marked time.Time, substr string) {
+	for i, p := range packets {
+		if !strings.Contains(string(p.Payload), substr) {
+			continue
+		}
+		if p.Time.After(marked) {
+			c.errorF("Packet %d arrived %v after the mark: %#v", i, p.Time.Sub(marked), string(p.Payload))
+		} else {
+			c.errorF("Packet %d arrived %v before the mark: %#v", i, marked.Sub(p.Time), string(p.Payload))
+		}
+	}
+}
+
+// ShouldNotReceiveAfterMark will fire a test error if a packet containing the
+// given string arrives after the given function calls mark. It may arrive
+// before; those packets are listed too when the assertion fails, for context.
+func (s *Server) ShouldNotReceiveAfterMark(t TestingT, substr string, body func(mark func()), opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets, marked := c.captureMarked(t, body, opts)
+	if marked.IsZero() {
+		c.printLocation(t)
+		c.errorF("Expected the body to call mark")
+		return
+	}
+	for _, p := range packets {
+		if p.Time.After(marked) && strings.Contains(string(p.Payload), substr) {
+			c.printLocation(t)
+			c.errorF("Expected no %#v after the mark", substr)
+			c.reportAroundMark(packets, marked, substr)
+			return
+		}
+	}
+}
+
+// ShouldNotReceiveAfterMark calls Server.ShouldNotReceiveAfterMark on the
+// default server.
+func ShouldNotReceiveAfterMark(t TestingT, substr string, body func(mark func()), opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceiveAfterMark(t, substr, body, opts...)
+}
+
+// ShouldOnlyReceiveAfterMark will fire a test error unless a packet containing
+// the given string arrives after the given function calls mark, and none
+// arrives before.
+func (s *Server) ShouldOnlyReceiveAfterMark(t TestingT, substr string, body func(mark func()), opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets, marked := c.captureMarked(t, body, opts)
+	if marked.IsZero() {
+		c.printLocation(t)
+		c.errorF("Expected the body to call mark")
+		return
+	}
+	before, after := 0, 0
+	for _, p := range packets {
+		if !strings.Contains(string(p.Payload), substr) {
+			continue
+		}
+		if p.Time.After(marked) {
+			after++
+		} else {
+			before++
+		}
+	}
+	if before > 0 {
+		c.printLocation(t)
+		c.errorF("Expected no %#v before the mark", substr)
+		c.reportAroundMark(packets, marked, substr)
+	} else if after == 0 {
+		c.printLocation(t)
+		c.errorF("Expected %#v after the mark", substr)
+		c.errorF("But got packets: %#v", payloadStrings(packets))
+	}
+}
+
+// ShouldOnlyReceiveAfterMark calls Server.ShouldOnlyReceiveAfterMark on the
+// default server.
+func ShouldOnlyReceiveAfterMark(t TestingT, substr string, body func(mark func()), opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldOnlyReceiveAfterMark(t, substr, body, opts...)
+}
@@ -0,0 +1,44 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldNotReceiveAfterMark(t *testing.T) {
+	udpClient := setup(t)
+	flip := func(before, after string) func(mark func()) {
+		return func(mark func()) {
+			udpClient.Write([]byte(before))
+			// Leave the reader time to see the packet before the mark.
+			time.Sleep(10 * time.Millisecond)
+			mark()
+			udpClient.Write([]byte(after))
+		}
+	}
+
+	ShouldNotReceiveAfterMark(t, "legacy", flip("legacy:1|c", "new:1|c"))
+	ShouldOnlyReceiveAfterMark(t, "new", flip("legacy:1|c", "new:1|c"))
+
+	ft := &fakeT{}
+	ShouldNotReceiveAfterMark(ft, "legacy", flip("legacy:1|c", "legacy:2|c"))
+	ShouldOnlyReceiveAfterMark(ft, "new", flip("new:1|c", "new:2|c"))
+	ShouldNotReceiveAfterMark(ft, "legacy", func(func()) {})
+	if len(ft.errors) != 3 {
+		t.Fatalf("Expected three failures, got %#v", ft.errors)
+	}
+	for i, want := range []string{
+		"Expected no \"legacy\" after the mark\nPacket 0 arrived ",
+		"Expected no \"new\" before the mark\nPacket 0 arrived ",
+		"Expected the body to call mark",
+	} {
+		if !strings.Contains(ft.errors[i], want) {
+			t.Errorf("Expected %#v in %#v", want, ft.errors[i])
+		}
+	}
+	if !strings.Contains(ft.errors[0], "before the mark: \"legacy:1|c\"\nPacket 1 arrived ") ||
+		!strings.HasSuffix(ft.errors[0], "after the mark: \"legacy:2|c\"") {
+		t.Errorf("Expected both packets with their offsets, got %#v", ft.errors[0])
+	}
+}
@@ -0,0 +1,209 @@
+package udp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Matcher checks the datagrams of a capture, returning why they fail it,
+// or nil if they pass.
+type Matcher interface {
+	Match(packets []string) error
+}
+
+// MatcherFunc adapts a function to a Matcher.
+type MatcherFunc func(packets []string) error
+
+// Match calls f.
+func (f MatcherFunc) Match(packets []string) error {
+	return f(packets)
+}
+
+// Equals matches if the datagrams, concatenated, are exactly expected, as
+// ShouldReceiveOnly checks.
+func Equals(expected string) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		if got := strings.Join(packets, ""); got != expected {
+			return fmt.Errorf("Expected: %#v", expected)
+		}
+		return nil
+	})
+}
+
+// Contains matches if the datagrams, concatenated, contain substr, as
+// ShouldReceive checks.
+func Contains(substr string) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		if !strings.Contains(strings.Join(packets, ""), substr) {
+			return fmt.Errorf("Expected to find: %#v", substr)
+		}
+		return nil
+	})
+}
+
+// NotContains matches unless the datagrams, concatenated, contain substr, as
+// ShouldNotReceive checks.
+func NotContains(substr string) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		if strings.Contains(strings.Join(packets, ""), substr) {
+			return fmt.Errorf("Expected not to find: %#v", substr)
+		}
+		return nil
+	})
+}
+
+// Matches matches if the datagrams, concatenated, match re, as
+// ShouldReceiveMatching checks.
+func Matches(re *regexp.Regexp) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		if !re.MatchString(strings.Join(packets, "")) {
+			return fmt.Errorf("Expected to match: %#v", re.String())
+		}
+		return nil
+	})
+}
+
+// ContainsAll matches if the datagrams, concatenated, contain every one of
+// substrs, as ShouldReceiveAll checks.
+func ContainsAll(substrs ...string) Matcher {
+	matchers := make([]Matcher, len(substrs))
+	for i, substr := range substrs {
+		matchers[i] = Contains(substr)
+	}
+	return AllOf(matchers...)
+}
+
+// ContainsAny matches if the datagrams, concatenated, contain at least one of
+// substrs.
+func ContainsAny(substrs ...string) Matcher {
+	matchers := make([]Matcher, len(substrs))
+	for i, substr := range substrs {
+		matchers[i] = Contains(substr)
+	}
+	return AnyOf(matchers...)
+}
+
+// AllOf matches if every one of matchers does. Its error lists the error of
+// each that doesn't, one per line.
+func AllOf(matchers ...Matcher) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		failures := []string{}
+		for _, m := range matchers {
+			if err := m.Match(packets); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+		if len(failures) > 0 {
+			return errors.New(strings.Join(failures, "\n"))
+		}
+		return nil
+	})
+}
+
+// AnyOf matches if at least one of matchers does. Its error lists the error
+// of each, one per line.
+func AnyOf(matchers ...Matcher) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		failures := []string{"Expected any of:"}
+		for _, m := range matchers {
+			err := m.Match(packets)
+			if err == nil {
+				return nil
+			}
+			failures = append(failures, "  "+strings.Replace(err.Error(), "\n", "\n  ", -1))
+		}
+		return errors.New(strings.Join(failures, "\n"))
+	})
+}
+
+// ShouldSatisfy will fire a test error with the error m returns for the
+// datagrams the given function sends over UDP, captured once. It lets
+// matchers written outside this package report failures as its own
+// assertions do.
+func (s *Server) ShouldSatisfy(t TestingT, m Matcher, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	if err := m.Match(packets); err != nil {
+		c.printLocation(t)
+		c.errorF("%v", err)
+		c.errorF("But got packets: %#v", packets)
+	}
+}
+
+// ShouldSatisfy calls Server.ShouldSatisfy on the default server.
+func ShouldSatisfy(t TestingT, m Matcher, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldSatisfy(t, m, body, opts...)
+}
+
+// PacketCount matches if there are exactly n datagrams.
+func PacketCount(n int) Matcher {
+	return MatcherFunc(func(packets []string) error {
+		if len(packets) != n {
+			return fmt.Errorf("Expected exactly %d packets, but got %d", n, len(packets))
+		}
+		return nil
+	})
+}
+
+// JSONSubset matches if one of the datagrams is JSON holding everything in
+// subset: every key of an object, recursively, and every element of an array,
+// position by position. Subset is compared as it would be decoded from JSON
+// once encoded, so 3 matches the number 3; a subset that can't be encoded
+// never matches.
+func JSONSubset(subset interface{}) Matcher {
+	want, err := normalizeJSON(subset)
+	return MatcherFunc(func(packets []string) error {
+		if err != nil {
+			return fmt.Errorf("Invalid JSON subset %#v: %v", subset, err)
+		}
+		for _, p := range packets {
+			var got interface{}
+			if json.Unmarshal([]byte(p), &got) == nil && jsonContains(got, want) {
+				return nil
+			}
+		}
+		want, _ := json.Marshal(subset)
+		return fmt.Errorf("Expected a JSON packet holding: %s", want)
+	})
+}
+
+// jsonContains reports whether got holds everything in want.
+func jsonContains(got, want interface{}) bool {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range want {
+			if g, ok := got[k]; !ok || !jsonContains(g, v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok || len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !jsonContains(got[i], want[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(got, want)
+	}
+}
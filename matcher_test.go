@@ -0,0 +1,65 @@
+package udp
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShouldSatisfy(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("hits:1|c\n"))
+		udpClient.Write([]byte("load:2|g"))
+	}
+
+	ShouldSatisfy(t, AllOf(
+		Equals("hits:1|c\nload:2|g"),
+		ContainsAll("hits", "load"),
+		ContainsAny("errors", "load"),
+		NotContains("errors"),
+		Matches(regexp.MustCompile(`load:\d`)),
+		PacketCount(2),
+	), send)
+
+	ft := &fakeT{}
+	ShouldSatisfy(ft, AnyOf(Contains("errors"), AllOf(PacketCount(1), Equals("load"))), send)
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], `
+Expected any of:
+  Expected to find: "errors"
+  Expected exactly 1 packets, but got 2
+  Expected: "load"
+But got packets: []string{"hits:1|c\n", "load:2|g"}`) {
+		t.Errorf("Expected every alternative to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	notEmpty := MatcherFunc(func(packets []string) error {
+		for _, p := range packets {
+			if p == "" {
+				return errors.New("Expected no empty packets")
+			}
+		}
+		return nil
+	})
+	ShouldSatisfy(ft, notEmpty, func() {
+		udpClient.Write([]byte{})
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "matcher_test.go:") || !strings.Contains(ft.errors[0], "Expected no empty packets") {
+		t.Errorf("Expected a custom matcher's error to be reported at the caller, got %#v", ft.errors)
+	}
+}
+
+func TestJSONSubsetNormalizes(t *testing.T) {
+	packets := []string{`{"n":3,"tags":["a"]}`}
+	if err := JSONSubset(map[string]interface{}{"n": 3, "tags": []string{"a"}}).Match(packets); err != nil {
+		t.Errorf("Expected Go values to match the JSON they encode to, got %v", err)
+	}
+	if err := JSONSubset(map[string]interface{}{"n": 4}).Match(packets); err == nil {
+		t.Errorf("Expected a different number not to match")
+	}
+	if err := JSONSubset(func() {}).Match(packets); err == nil || !strings.Contains(err.Error(), "Invalid JSON subset") {
+		t.Errorf("Expected a subset that can't be encoded to be reported, got %v", err)
+	}
+}
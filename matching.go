@@ -0,0 +1,353 @@
+package udp
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// patterns caches the regular expressions the Matching assertions compile.
+var patterns sync.Map
+
+// compilePattern compiles pattern, or returns it from the cache. It fails the
+// test, naming the pattern and the assertion's location, if pattern is not a
+// valid regular expression.
+func compilePattern(t TestingT, pattern string) *regexp.Regexp {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatal(fmt.Sprintf("udp: invalid pattern at %s: %v", callerLocation(), err))
+		return nil
+	}
+	patterns.Store(pattern, re)
+	return re
+}
+
+// WithPerPacket makes the Matching assertions match each packet on its own,
+// rather than everything received, concatenated.
+func WithPerPacket() Option {
+	return func(c *callConfig) {
+		c.perPacket = true
+	}
+}
+
+// matchSubjects returns what the Matching assertions match against: everything
+// the given function sends, concatenated, or each packet with WithPerPacket.
+func (c *call) matchSubjects(t TestingT, body fn, cfg *callConfig) []string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if cfg.perPacket {
+		return payloadStrings(c.capturePackets(t, body, cfg))
+	}
+	return []string{c.captureMessage(t, body, false, cfg)}
+}
+
+// reportSubjects reports what was matched against.
+func (c *call) reportSubjects(subjects []string, cfg *callConfig) {
+	if cfg.perPacket {
+		c.errorF("But got packets: %#v", subjects)
+	} else {
+		c.errorF("But got: %#v", subjects[0])
+	}
+}
+
+func anyMatches(re *regexp.Regexp, subjects []string) bool {
+	for _, subject := range subjects {
+		if re.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldReceiveMatching will fire a test error if the given function doesn't
+// send anything over UDP matching the given regular expression. The pattern
+// is matched against everything received, concatenated, or against each
+// packet with WithPerPacket.
+func (s *Server) ShouldReceiveMatching(t TestingT, pattern string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	re := compilePattern(t, pattern)
+	if re == nil {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.matchSubjects(t, body, cfg)
+	if !anyMatches(re, got) {
+		c.printLocation(t)
+		c.errorF("Expected to match: %#v", pattern)
+		c.reportSubjects(got, cfg)
+	}
+}
+
+// ShouldReceiveMatching calls Server.ShouldReceiveMatching on the default
+// server.
+func ShouldReceiveMatching(t TestingT, pattern string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveMatching(t, pattern, body, opts...)
+}
+
+// ShouldNotReceiveMatching will fire a test error if what the given function
+// sends over UDP, concatenated, matches the given regular expression. With
+// WithPerPacket it fires if any one packet matches.
+func (s *Server) ShouldNotReceiveMatching(t TestingT, pattern string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	re := compilePattern(t, pattern)
+	if re == nil {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.matchSubjects(t, body, cfg)
+	if anyMatches(re, got) {
+		c.printLocation(t)
+		c.errorF("Expected not to match: %#v", pattern)
+		c.reportSubjects(got, cfg)
+	}
+}
+
+// ShouldNotReceiveMatching calls Server.ShouldNotReceiveMatching on the
+// default server.
+func ShouldNotReceiveMatching(t TestingT, pattern string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceiveMatching(t, pattern, body, opts...)
+}
+
+// ShouldReceiveAllMatching will fire a test error unless what the given
+// function sends over UDP, concatenated, matches every one of the given
+// regular expressions. With WithPerPacket each must match some packet.
+func (s *Server) ShouldReceiveAllMatching(t TestingT, patterns []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		if res[i] = compilePattern(t, pattern); res[i] == nil {
+			return
+		}
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.matchSubjects(t, body, cfg)
+	failed := false
+
+	for i, re := range res {
+		if !anyMatches(re, got) {
+			if !failed {
+				c.printLocation(t)
+				failed = true
+			}
+			c.errorF("Expected to match: %#v", patterns[i])
+		}
+	}
+
+	if failed {
+		c.reportSubjects(got, cfg)
+	}
+}
+
+// ShouldReceiveAllMatching calls Server.ShouldReceiveAllMatching on the
+// default server.
+func ShouldReceiveAllMatching(t TestingT, patterns []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAllMatching(t, patterns, body, opts...)
+}
+
+// checkPredicate fails the test, naming desc and the assertion's location, if
+// pred is nil.
+func checkPredicate(t TestingT, desc string, pred func(packet string) bool) bool {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if pred == nil {
+		t.Fatal(fmt.Sprintf("udp: nil predicate %q at %s", desc, callerLocation()))
+		return false
+	}
+	return true
+}
+
+// ShouldReceiveWhere will fire a test error unless one of the datagrams the
+// given function sends over UDP satisfies pred. desc describes pred in the
+// failure message.
+func (s *Server) ShouldReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !checkPredicate(t, desc, pred) {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	for _, p := range packets {
+		if pred(p) {
+			return
+		}
+	}
+	c.printLocation(t)
+	c.errorF("Expected a packet where: %s", desc)
+	c.errorF("But got packets: %#v", packets)
+}
+
+// ShouldReceiveWhere calls Server.ShouldReceiveWhere on the default server.
+func ShouldReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveWhere(t, desc, pred, body, opts...)
+}
+
+// ShouldAllReceiveWhere will fire a test error unless the given function sends
+// something over UDP and every datagram it sends satisfies pred. desc
+// describes pred in the failure message, which lists the datagrams that don't.
+func (s *Server) ShouldAllReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !checkPredicate(t, desc, pred) {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	if len(packets) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected every packet to be where: %s", desc)
+		c.errorF("But got no packets")
+		return
+	}
+	failed := false
+	for i, p := range packets {
+		if !pred(p) {
+			if !failed {
+				c.printLocation(t)
+				c.errorF("Expected every packet to be where: %s", desc)
+				c.errorF("But these are not:")
+				failed = true
+			}
+			c.errorF("%d: %#v", i, p)
+		}
+	}
+}
+
+// ShouldAllReceiveWhere calls Server.ShouldAllReceiveWhere on the default
+// server.
+func ShouldAllReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldAllReceiveWhere(t, desc, pred, body, opts...)
+}
+
+// ShouldNotReceiveWhere will fire a test error if any of the datagrams the
+// given function sends over UDP satisfies pred. desc describes pred in the
+// failure message, which lists the datagrams that do.
+func (s *Server) ShouldNotReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !checkPredicate(t, desc, pred) {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	failed := false
+	for i, p := range packets {
+		if pred(p) {
+			if !failed {
+				c.printLocation(t)
+				c.errorF("Expected no packet where: %s", desc)
+				c.errorF("But these are:")
+				failed = true
+			}
+			c.errorF("%d: %#v", i, p)
+		}
+	}
+}
+
+// ShouldNotReceiveWhere calls Server.ShouldNotReceiveWhere on the default
+// server.
+func ShouldNotReceiveWhere(t TestingT, desc string, pred func(packet string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceiveWhere(t, desc, pred, body, opts...)
+}
+
+// ShouldReceiveMessageWhere will fire a test error unless what the given
+// function sends over UDP, concatenated, satisfies pred. desc describes pred
+// in the failure message.
+func (s *Server) ShouldReceiveMessageWhere(t TestingT, desc string, pred func(msg string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !checkPredicate(t, desc, pred) {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, true, newCallConfig(opts))
+	if !pred(got) {
+		c.printLocation(t)
+		c.errorF("Expected a message where: %s", desc)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldReceiveMessageWhere calls Server.ShouldReceiveMessageWhere on the
+// default server.
+func ShouldReceiveMessageWhere(t TestingT, desc string, pred func(msg string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveMessageWhere(t, desc, pred, body, opts...)
+}
+
+// ShouldNotReceiveMessageWhere will fire a test error if what the given
+// function sends over UDP, concatenated, satisfies pred. desc describes pred
+// in the failure message.
+func (s *Server) ShouldNotReceiveMessageWhere(t TestingT, desc string, pred func(msg string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !checkPredicate(t, desc, pred) {
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, false, newCallConfig(opts))
+	if pred(got) {
+		c.printLocation(t)
+		c.errorF("Expected no message where: %s", desc)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldNotReceiveMessageWhere calls Server.ShouldNotReceiveMessageWhere on
+// the default server.
+func ShouldNotReceiveMessageWhere(t TestingT, desc string, pred func(msg string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceiveMessageWhere(t, desc, pred, body, opts...)
+}
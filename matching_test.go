@@ -0,0 +1,109 @@
+package udp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestShouldReceiveMatching(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveMatching(t, `^api\.latency:[0-9.]+\|ms$`, func() {
+		udpClient.Write([]byte("api.latency:12.5|ms"))
+	})
+	ShouldNotReceiveMatching(t, `\|c$`, func() {
+		udpClient.Write([]byte("api.latency:12.5|ms"))
+	})
+	ShouldReceiveAllMatching(t, []string{`latency:\d+`, `hits:\d+\|c`}, func() {
+		udpClient.Write([]byte("api.latency:12|ms\n"))
+		udpClient.Write([]byte("api.hits:3|c"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveAllMatching(ft, []string{`latency:\d+`, `errors:\d+`}, func() {
+		udpClient.Write([]byte("api.latency:12|ms"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected to match: \"errors:\\\\d+\"\nBut got: \"api.latency:12|ms\"") {
+		t.Errorf("Expected the unmatched pattern to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldReceiveMatching(ft, `(`, func() {})
+	if len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "udp: invalid pattern at ") ||
+		!strings.Contains(ft.fatals[0], "matching_test.go:") || !strings.Contains(ft.fatals[0], "missing closing ): `(`") {
+		t.Errorf("Expected an invalid pattern to be fatal, got %#v", ft.fatals)
+	}
+}
+
+func TestMatchingPerPacket(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("api.hits:3|"))
+		udpClient.Write([]byte("c"))
+	}
+
+	ShouldReceiveMatching(t, `:\d+\|c$`, send)
+	ShouldNotReceiveMatching(t, `:\d+\|c$`, send, WithPerPacket())
+	ShouldReceiveAllMatching(t, []string{`^api`, `^c$`}, send, WithPerPacket())
+
+	ft := &fakeT{}
+	ShouldReceiveMatching(ft, `:\d+\|c$`, send, WithPerPacket())
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Expected to match: \":\\\\d+\\\\|c$\"\nBut got packets: []string{\"api.hits:3|\", \"c\"}") {
+		t.Errorf("Expected the packets to be listed, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveWhere(t *testing.T) {
+	udpClient := setup(t)
+	isCounter := func(p string) bool { return strings.HasSuffix(p, "|c") }
+	send := func() {
+		udpClient.Write([]byte("hits:1|c"))
+		udpClient.Write([]byte("load:2|g"))
+	}
+
+	ShouldReceiveWhere(t, "is a counter", isCounter, send)
+
+	ft := &fakeT{}
+	ShouldAllReceiveWhere(ft, "is a counter", isCounter, send)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected every packet to be where: is a counter\nBut these are not:\n1: \"load:2|g\"") {
+		t.Errorf("Expected the offending packet to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldReceiveWhere(ft, "is a timer", func(p string) bool { return strings.HasSuffix(p, "|ms") }, send)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected a packet where: is a timer\nBut got packets: []string{\"hits:1|c\", \"load:2|g\"}") {
+		t.Errorf("Expected the description and packets to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ran := false
+	ShouldAllReceiveWhere(ft, "is valid", nil, func() { ran = true })
+	if ran || len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "udp: nil predicate \"is valid\" at ") {
+		t.Errorf("Expected a nil predicate to be fatal, got %#v", ft.fatals)
+	}
+}
+
+func TestShouldReceiveMessageWhere(t *testing.T) {
+	udpClient := setup(t)
+	gaugeInRange := func(msg string) bool {
+		v, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(msg, "load:"), "|g"))
+		return err == nil && strings.HasPrefix(msg, "load:") && v >= 10 && v <= 20
+	}
+	send := func() {
+		udpClient.Write([]byte("load:"))
+		udpClient.Write([]byte("15|g"))
+	}
+
+	ShouldReceiveMessageWhere(t, "gauge value between 10 and 20", gaugeInRange, send)
+	ShouldNotReceiveWhere(t, "gauge value between 10 and 20", gaugeInRange, send)
+
+	ft := &fakeT{}
+	ShouldNotReceiveMessageWhere(ft, "gauge value between 10 and 20", gaugeInRange, send)
+	ShouldNotReceiveWhere(ft, "is a gauge", func(p string) bool { return strings.HasSuffix(p, "|g") }, send)
+	if len(ft.errors) != 2 ||
+		!strings.Contains(ft.errors[0], "Expected no message where: gauge value between 10 and 20\nBut got: \"load:15|g\"") ||
+		!strings.Contains(ft.errors[1], "Expected no packet where: is a gauge\nBut these are:\n1: \"15|g\"") {
+		t.Errorf("Expected the description and what was received to be reported, got %#v", ft.errors)
+	}
+}
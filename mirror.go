@@ -0,0 +1,91 @@
+package udp
+
+import (
+	"reflect"
+	"time"
+)
+
+// WithNormalizer makes ShouldMirrorAcross compare payloads once passed through
+// normalize, for example to blank out a field that differs between legs.
+// Normalizers run in the order they are given.
+func WithNormalizer(normalize func(payload []byte) []byte) Option {
+	return func(c *callConfig) {
+		c.normalizers = append(c.normalizers, normalize)
+	}
+}
+
+// WithStrictOrder makes ShouldMirrorAcross also require every address to
+// receive its packets in the same order.
+func WithStrictOrder() Option {
+	return func(c *callConfig) {
+		c.strictOrder = true
+	}
+}
+
+// ShouldMirrorAcross will fire a test error unless every one of the given
+// addresses receives the same packets while the given function runs, in any
+// order unless WithStrictOrder is given. Each address is compared with the
+// first, and the packets it is missing or has in excess are reported.
+func (s *Server) ShouldMirrorAcross(t TestingT, addrs []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	if !applyBudget(t, c.s, cfg) {
+		return
+	}
+	defer chargeBudget(t, time.Now())
+	conns, ok := listenAll(t, addrs)
+	defer closeAll(conns)
+	if !ok {
+		return
+	}
+	packets, _ := c.readPacketsFrom(conns, body, cfg)
+
+	legs := make([][]string, len(conns))
+	for _, p := range packets {
+		payload := p.Payload
+		for _, normalize := range cfg.normalizers {
+			payload = normalize(payload)
+		}
+		legs[p.Listener] = append(legs[p.Listener], string(payload))
+	}
+
+	failed := false
+	fail := func() {
+		if !failed {
+			c.printLocation(t)
+			c.errorF("Expected every address to receive the same packets as %s", addrs[0])
+			failed = true
+		}
+	}
+	for i := 1; i < len(legs); i++ {
+		missing, extra := multisetDiff(legs[0], legs[i])
+		if len(missing) > 0 || len(extra) > 0 {
+			fail()
+			c.errorF("%s got %d packets to %d", addrs[i], len(legs[i]), len(legs[0]))
+			if len(missing) > 0 {
+				c.errorF("Missing: %#v", missing)
+			}
+			if len(extra) > 0 {
+				c.errorF("Extra: %#v", extra)
+			}
+		} else if cfg.strictOrder && !reflect.DeepEqual(legs[0], legs[i]) {
+			fail()
+			c.errorF("%s got the packets in another order: %#v", addrs[i], legs[i])
+		}
+	}
+	if failed {
+		c.errorF("While %s got: %#v", addrs[0], legs[0])
+	}
+}
+
+// ShouldMirrorAcross calls Server.ShouldMirrorAcross on the default server.
+func ShouldMirrorAcross(t TestingT, addrs []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldMirrorAcross(t, addrs, body, opts...)
+}
@@ -0,0 +1,76 @@
+package udp
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+// mirror sends each payload to every address, and leaves out the last address
+// for the payloads in dropLast.
+func mirror(t *testing.T, addrs []string, payloads []string, dropLast map[string]bool) {
+	for i, a := range addrs {
+		conn, err := net.Dial("udp", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range payloads {
+			if i == len(addrs)-1 && dropLast[p] {
+				continue
+			}
+			conn.Write([]byte(p))
+		}
+		conn.Close()
+	}
+}
+
+func TestShouldMirrorAcross(t *testing.T) {
+	addrs := freeAddrs(t, 3)
+
+	ShouldMirrorAcross(t, addrs, func() {
+		mirror(t, addrs, []string{"a", "b", "b"}, nil)
+	}, WithStrictOrder())
+
+	ft := &fakeT{}
+	ShouldMirrorAcross(ft, addrs, func() {
+		mirror(t, addrs, []string{"a", "b"}, map[string]bool{"b": true})
+	})
+	want := "Expected every address to receive the same packets as " + addrs[0] + "\n" +
+		addrs[2] + " got 1 packets to 2\nMissing: []string{\"b\"}\nWhile " + addrs[0] + " got: []string{\"a\", \"b\"}"
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) {
+		t.Errorf("Expected the dropped leg to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldMirrorAcross(ft, addrs[:2], func() {
+		mirror(t, addrs[:1], []string{"a", "b"}, nil)
+		mirror(t, addrs[1:2], []string{"b", "a"}, nil)
+	}, WithStrictOrder())
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], addrs[1]+" got the packets in another order: []string{\"b\", \"a\"}") {
+		t.Errorf("Expected the reordered leg to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestShouldMirrorAcrossNormalizes(t *testing.T) {
+	addrs := freeAddrs(t, 2)
+	send := func() {
+		for i, a := range addrs {
+			conn, err := net.Dial("udp", a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Write([]byte{'x', byte('0' + i)})
+			conn.Close()
+		}
+	}
+
+	ft := &fakeT{}
+	ShouldMirrorAcross(ft, addrs, send)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Extra: []string{\"x1\"}") {
+		t.Errorf("Expected differing payloads to fail, got %#v", ft.errors)
+	}
+	ShouldMirrorAcross(t, addrs, send, WithNormalizer(func(b []byte) []byte {
+		return bytes.TrimRight(b, "0123456789")
+	}))
+}
@@ -0,0 +1,83 @@
+package udp
+
+// WithFatal makes the assertion fail the test with Fatal rather than Error,
+// stopping it there, with the same message: what was expected, what was got
+// and where.
+func WithFatal() Option {
+	return func(c *callConfig) {
+		c.fatal = true
+	}
+}
+
+// withFatal returns a copy of opts with WithFatal added, leaving the caller's
+// slice alone.
+func withFatal(opts []Option) []Option {
+	return append(append([]Option(nil), opts...), WithFatal())
+}
+
+// MustReceive is ShouldReceive, but stops the test with Fatal if it fails.
+func (s *Server) MustReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.ShouldReceive(t, expected, body, withFatal(opts)...)
+}
+
+// MustReceive calls Server.MustReceive on the default server.
+func MustReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.MustReceive(t, expected, body, opts...)
+}
+
+// MustReceiveOnly is ShouldReceiveOnly, but stops the test with Fatal if it
+// fails.
+func (s *Server) MustReceiveOnly(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.ShouldReceiveOnly(t, expected, body, withFatal(opts)...)
+}
+
+// MustReceiveOnly calls Server.MustReceiveOnly on the default server.
+func MustReceiveOnly(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.MustReceiveOnly(t, expected, body, opts...)
+}
+
+// MustReceiveAll is ShouldReceiveAll, but stops the test with Fatal if it
+// fails, listing every string not found.
+func (s *Server) MustReceiveAll(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.ShouldReceiveAll(t, expected, body, withFatal(opts)...)
+}
+
+// MustReceiveAll calls Server.MustReceiveAll on the default server.
+func MustReceiveAll(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.MustReceiveAll(t, expected, body, opts...)
+}
+
+// MustReceivePacket is ShouldReceivePacket, but stops the test with Fatal if
+// it fails.
+func (s *Server) MustReceivePacket(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.ShouldReceivePacket(t, expected, body, withFatal(opts)...)
+}
+
+// MustReceivePacket calls Server.MustReceivePacket on the default server.
+func MustReceivePacket(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.MustReceivePacket(t, expected, body, opts...)
+}
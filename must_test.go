@@ -0,0 +1,74 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMust(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	}
+
+	ft := &fakeT{}
+	MustReceive(ft, "foo", send)
+	MustReceiveOnly(ft, "foobar", send)
+	MustReceiveAll(ft, []string{"foo", "bar"}, send)
+	MustReceivePacket(ft, "bar", send)
+	if len(ft.fatals) != 0 || len(ft.errors) != 0 {
+		t.Errorf("Expected passing Must assertions to report nothing, got %q and %q", ft.fatals, ft.errors)
+	}
+
+	for name, must := range map[string]func(TestingT){
+		"MustReceive":       func(t TestingT) { MustReceive(t, "baz", send) },
+		"MustReceiveOnly":   func(t TestingT) { MustReceiveOnly(t, "foo", send) },
+		"MustReceiveAll":    func(t TestingT) { MustReceiveAll(t, []string{"foo", "baz"}, send) },
+		"MustReceivePacket": func(t TestingT) { MustReceivePacket(t, "foobar", send) },
+	} {
+		ft := &fakeT{}
+		must(ft)
+		if len(ft.fatals) != 1 || len(ft.errors) != 0 {
+			t.Errorf("%s: expected exactly one Fatal and no errors, got %q and %q", name, ft.fatals, ft.errors)
+			continue
+		}
+		for _, want := range []string{"Expected", "But got", "At: "} {
+			if !strings.Contains(ft.fatals[0], want) {
+				t.Errorf("%s: expected the Fatal to contain %q, got %q", name, want, ft.fatals[0])
+			}
+		}
+	}
+}
+
+func TestWithFatalOnEveryPath(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldDrainWithin(ft, 5*time.Millisecond, flushLater(udpClient, 2, 20*time.Millisecond), WithTimeout(50*time.Millisecond), WithFatal())
+	if len(ft.fatals) != 1 || len(ft.errors) != 0 {
+		t.Errorf("Expected ShouldDrainWithin to fail with Fatal, got %q and %q", ft.fatals, ft.errors)
+	}
+
+	addrs := freeAddrs(t, 2)
+	ft = &fakeT{}
+	ShouldMirrorAcross(ft, addrs, func() {
+		mirror(t, addrs, []string{"a", "b"}, map[string]bool{"b": true})
+	}, WithFatal())
+	if len(ft.fatals) != 1 || len(ft.errors) != 0 {
+		t.Errorf("Expected ShouldMirrorAcross to fail with Fatal, got %q and %q", ft.fatals, ft.errors)
+	}
+}
+
+func TestMustLeavesOptionsAlone(t *testing.T) {
+	udpClient := setup(t)
+
+	opts := make([]Option, 1, 2)
+	opts[0] = WithTimeout(50 * time.Millisecond)
+	ft := &fakeT{}
+	MustReceive(ft, "foo", func() { udpClient.Write([]byte("foo")) }, opts...)
+	if len(ft.fatals) != 0 || opts[:2][1] != nil {
+		t.Errorf("Expected the caller's options to be left alone, got %q", ft.fatals)
+	}
+}
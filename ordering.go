@@ -0,0 +1,91 @@
+package udp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// orderingViolation returns why packets break the rule that every packet
+// containing later comes after one containing earlier, or "" if they don't.
+func orderingViolation(packets []string, earlier, later string) string {
+	first := -1
+	for i, p := range packets {
+		if strings.Contains(p, earlier) {
+			first = i
+			break
+		}
+	}
+	for i, p := range packets {
+		j := strings.Index(p, later)
+		if j < 0 || (first >= 0 && first < i) {
+			continue
+		}
+		at := fmt.Sprintf("packet %d at [%d:%d]", i, j, j+len(later))
+		if first < 0 {
+			return fmt.Sprintf("Expected %#v before %#v, but %#v is in %s and %#v is in no packet", earlier, later, later, at, earlier)
+		}
+		return fmt.Sprintf("Expected %#v before %#v, but %#v is in %s and %#v is first in packet %d", earlier, later, later, at, earlier, first)
+	}
+	return ""
+}
+
+// ShouldReceiveBefore will fire a test error unless every datagram the given
+// function sends over UDP containing later comes after a datagram containing
+// earlier. It says nothing about datagrams in between, nor fails if none
+// contains later.
+func (s *Server) ShouldReceiveBefore(t TestingT, earlier, later string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.ShouldRespectOrdering(t, [][2]string{{earlier, later}}, body, opts...)
+}
+
+// ShouldReceiveBefore calls Server.ShouldReceiveBefore on the default server.
+func ShouldReceiveBefore(t TestingT, earlier, later string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveBefore(t, earlier, later, body, opts...)
+}
+
+// ShouldRespectOrdering will fire a test error unless the datagrams the given
+// function sends over UDP respect every pair, as ShouldReceiveBefore checks
+// for pair[0] and pair[1]. Every pair is checked against the same capture,
+// and each one broken is reported with the first packet breaking it.
+func (s *Server) ShouldRespectOrdering(t TestingT, pairs [][2]string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	failed := false
+
+	for _, pair := range pairs {
+		violation := orderingViolation(packets, pair[0], pair[1])
+		if violation == "" {
+			continue
+		}
+		if !failed {
+			c.printLocation(t)
+			failed = true
+		}
+		c.errorF("%s", violation)
+	}
+
+	if failed {
+		c.errorF("But got packets:")
+		for i, p := range packets {
+			c.errorF("%d: %#v", i, p)
+		}
+	}
+}
+
+// ShouldRespectOrdering calls Server.ShouldRespectOrdering on the default
+// server.
+func ShouldRespectOrdering(t TestingT, pairs [][2]string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldRespectOrdering(t, pairs, body, opts...)
+}
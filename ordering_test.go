@@ -0,0 +1,42 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShouldRespectOrdering(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("config.loaded"))
+		udpClient.Write([]byte("request.handled"))
+		udpClient.Write([]byte("shutdown.complete"))
+		udpClient.Write([]byte("request.handled"))
+		udpClient.Write([]byte("shutdown.begin"))
+	}
+
+	ShouldReceiveBefore(t, "config.loaded", "request.handled", send)
+	ShouldReceiveBefore(t, "config.loaded", "cache.miss", send)
+
+	ft := &fakeT{}
+	ShouldRespectOrdering(ft, [][2]string{
+		{"config.loaded", "request.handled"},
+		{"shutdown.begin", "shutdown.complete"},
+		{"auth.ready", "request.handled"},
+	}, send)
+	if len(ft.errors) != 1 {
+		t.Fatalf("Expected one error, got %#v", ft.errors)
+	}
+	for _, expected := range []string{
+		`Expected "shutdown.begin" before "shutdown.complete", but "shutdown.complete" is in packet 2 at [0:17] and "shutdown.begin" is first in packet 4`,
+		`Expected "auth.ready" before "request.handled", but "request.handled" is in packet 1 at [0:15] and "auth.ready" is in no packet`,
+		"But got packets:\n0: \"config.loaded\"\n",
+	} {
+		if !strings.Contains(ft.errors[0], expected) {
+			t.Errorf("Expected %q to be reported, got %s", expected, ft.errors[0])
+		}
+	}
+	if strings.Contains(ft.errors[0], `Expected "config.loaded" before`) {
+		t.Errorf("Expected the respected pair not to be reported, got %s", ft.errors[0])
+	}
+}
@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultPartition is the partition of the packets the key function of
+// WithPartitionBy returns no key for.
+const DefaultPartition = ""
+
+// WithPartitionBy splits a capture into partitions by the key the given
+// function extracts from each packet, such as a tenant ID, so that
+// ShouldReceiveInPartition and Capture.Partitions only see one at a time.
+func WithPartitionBy(key func(payload []byte) string) Option {
+	return func(c *callConfig) {
+		c.partitionBy = key
+	}
+}
+
+// Partitions returns the capture's packets split by the key function of
+// WithPartitionBy, in their order within the capture. Without one, every
+// packet is in DefaultPartition.
+func (c *Capture) Partitions() map[string]*Capture {
+	partitions := map[string]*Capture{}
+	for _, p := range c.Packets {
+		key := DefaultPartition
+		if c.partitionBy != nil {
+			key = c.partitionBy(p.Payload)
+		}
+		part := partitions[key]
+		if part == nil {
+			part = &Capture{Transforms: c.Transforms, partitionBy: c.partitionBy}
+			partitions[key] = part
+		}
+		part.Packets = append(part.Packets, p)
+	}
+	return partitions
+}
+
+// ShouldReceiveInPartition will fire a test error unless the packets the given
+// function sends over UDP to the given partition, concatenated, contain the
+// given string. The partitions are set up with WithPartitionBy, which must be
+// among opts.
+func (s *Server) ShouldReceiveInPartition(t TestingT, partition string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	cfg := newCallConfig(opts)
+	if cfg.partitionBy == nil {
+		t.Fatal("udp: ShouldReceiveInPartition needs WithPartitionBy")
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	capture := &Capture{Packets: c.capturePackets(t, body, cfg), partitionBy: cfg.partitionBy}
+	partitions := capture.Partitions()
+	got := ""
+	if part := partitions[partition]; part != nil {
+		got = part.String()
+	}
+	if strings.Contains(got, expected) {
+		return
+	}
+
+	others := []string{}
+	for key := range partitions {
+		if key != partition {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	c.printLocation(t)
+	c.errorF("Expected in partition %#v: %#v", partition, expected)
+	c.errorF("But it got: %#v", got)
+	c.errorF("Other partitions with packets: %#v", others)
+}
+
+// ShouldReceiveInPartition calls Server.ShouldReceiveInPartition on the
+// default server.
+func ShouldReceiveInPartition(t TestingT, partition string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveInPartition(t, partition, expected, body, opts...)
+}
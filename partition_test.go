@@ -0,0 +1,62 @@
+package udp
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var tenantRe = regexp.MustCompile(`tenant=(\w+)`)
+
+func tenantOf(payload []byte) string {
+	if m := tenantRe.FindSubmatch(payload); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func TestShouldReceiveInPartition(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("orders.created tenant=a"))
+		udpClient.Write([]byte("orders.deleted tenant=b"))
+		udpClient.Write([]byte("heartbeat"))
+	}
+
+	ShouldReceiveInPartition(t, "a", "orders.created", send, WithPartitionBy(tenantOf))
+	ShouldReceiveInPartition(t, DefaultPartition, "heartbeat", send, WithPartitionBy(tenantOf))
+
+	parts := ReceiveCapture(t, send, WithPartitionBy(tenantOf)).Partitions()
+	if len(parts) != 3 || parts["b"].String() != "orders.deleted tenant=b" {
+		t.Errorf("Expected three partitions, got %#v", parts)
+	}
+
+	ft := &fakeT{}
+	ShouldReceiveInPartition(ft, "b", "orders.created", send, WithPartitionBy(tenantOf))
+	ShouldReceiveInPartition(ft, "a", "orders.created", send)
+	want := "Expected in partition \"b\": \"orders.created\"\nBut it got: \"orders.deleted tenant=b\"\n" +
+		"Other partitions with packets: []string{\"\", \"a\"}"
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) || len(ft.fatals) != 1 {
+		t.Errorf("Expected the partition searched and the others, got %#v %#v", ft.errors, ft.fatals)
+	}
+}
+
+func TestReceiveCaptureFromPartitions(t *testing.T) {
+	addrs := freeAddrs(t, 2)
+	send := func() {
+		for i, payload := range []string{"orders.created tenant=a", "orders.deleted tenant=b"} {
+			conn, err := net.Dial("udp", addrs[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Write([]byte(payload))
+			conn.Close()
+		}
+	}
+
+	parts := ReceiveCaptureFrom(t, addrs, send, WithPartitionBy(tenantOf)).Partitions()
+	if len(parts) != 2 || parts["a"].String() != "orders.created tenant=a" || parts["b"].String() != "orders.deleted tenant=b" {
+		t.Errorf("Expected a partition per tenant, got %#v", parts)
+	}
+}
@@ -0,0 +1,107 @@
+package udp
+
+import (
+	"math/rand"
+	"time"
+)
+
+// maxShrinks bounds how many smaller payloads are tried after a failure.
+const maxShrinks = 1000
+
+type logger interface {
+	Logf(format string, args ...interface{})
+}
+
+// WithSeed makes Property start from the given seed instead of a random one,
+// to deterministically rerun a reported failure.
+func WithSeed(seed int64) Option {
+	return func(c *callConfig) {
+		c.seed = &seed
+	}
+}
+
+// WithShrink lets Property look for a smaller failing payload. shrink returns
+// candidate payloads simpler than the one given, and send sends a candidate to
+// addr the way the generated send function would have.
+func WithShrink(shrink func(payload interface{}) []interface{}, send func(payload interface{}, addr string)) Option {
+	return func(c *callConfig) {
+		c.shrink = shrink
+		c.shrinkSend = send
+	}
+}
+
+// Property runs a property based test: on each of the given number of
+// iterations gen builds a random payload and a function sending it, the
+// capture of that send is handed to check, and any error check returns fails
+// the test with the payload and the seed needed to reproduce it.
+//
+// Every iteration gets its own seed, so a failure reported at seed N can be
+// rerun on its own with Property(t, 1, gen, check, WithSeed(N)).
+func (s *Server) Property(t TestingT, iterations int, gen func(r *rand.Rand) (payload interface{}, send func(addr string)), check func(payload interface{}, c *Capture) error, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	seed := time.Now().UnixNano()
+	if cfg.seed != nil {
+		seed = *cfg.seed
+	}
+	if l, ok := t.(logger); ok {
+		l.Logf("udp: property seed %d", seed)
+	}
+
+	for i := 0; i < iterations; i++ {
+		iterSeed := seed + int64(i)
+		payload, send := gen(rand.New(rand.NewSource(iterSeed)))
+		err := check(payload, c.getCapture(t, func() { send(s.Addr()) }, cfg))
+		if err == nil {
+			continue
+		}
+
+		c.printLocation(t)
+		c.errorF("Property failed on iteration %d, rerun with WithSeed(%d)", i, iterSeed)
+		c.errorF("Payload: %#v", payload)
+		c.errorF("Error: %v", err)
+		if cfg.shrink != nil {
+			if smallest, smallestErr := c.shrinkPayload(t, cfg, payload, check); smallestErr != nil {
+				c.errorF("Shrunk payload: %#v", smallest)
+				c.errorF("Error: %v", smallestErr)
+			}
+		}
+		return
+	}
+}
+
+// Property calls Server.Property on the default server.
+func Property(t TestingT, iterations int, gen func(r *rand.Rand) (payload interface{}, send func(addr string)), check func(payload interface{}, c *Capture) error, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.Property(t, iterations, gen, check, opts...)
+}
+
+// shrinkPayload repeatedly replaces the failing payload with the first of its
+// shrink candidates that still fails, until none do.
+func (c *call) shrinkPayload(t TestingT, cfg *callConfig, payload interface{}, check func(interface{}, *Capture) error) (interface{}, error) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	var smallestErr error
+	for tries := 0; tries < maxShrinks; {
+		shrunk := false
+		for _, candidate := range cfg.shrink(payload) {
+			tries++
+			err := check(candidate, c.getCapture(t, func() { cfg.shrinkSend(candidate, c.s.Addr()) }, cfg))
+			if err != nil {
+				payload, smallestErr, shrunk = candidate, err, true
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return payload, smallestErr
+}
@@ -0,0 +1,73 @@
+package udp
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sendCounter(n int, addr string) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "hits:%d|c", n)
+}
+
+func genCounter(r *rand.Rand) (interface{}, func(addr string)) {
+	n := r.Intn(1000)
+	return n, func(addr string) { sendCounter(n, addr) }
+}
+
+func checkCounter(payload interface{}, c *Capture) error {
+	var n int
+	if _, err := fmt.Sscanf(c.String(), "hits:%d|c", &n); err != nil {
+		return err
+	}
+	if n != payload.(int) || n > 100 {
+		return fmt.Errorf("decoded %d", n)
+	}
+	return nil
+}
+
+func TestProperty(t *testing.T) {
+	setup(t)
+
+	Property(t, 20, func(r *rand.Rand) (interface{}, func(addr string)) {
+		n := r.Intn(100)
+		return n, func(addr string) { sendCounter(n, addr) }
+	}, checkCounter)
+}
+
+func TestPropertyShrinks(t *testing.T) {
+	setup(t)
+
+	ft := &fakeT{}
+	halve := func(payload interface{}) []interface{} {
+		return []interface{}{payload.(int) / 2, payload.(int) - 1}
+	}
+	send := func(payload interface{}, addr string) { sendCounter(payload.(int), addr) }
+	Property(ft, 50, genCounter, checkCounter, WithSeed(1), WithShrink(halve, send))
+
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Shrunk payload") || !strings.Contains(ft.errors[0], "101") {
+		t.Errorf("Expected a failure shrunk to 101, got %#v", ft.errors)
+	}
+}
+
+func TestPropertyPassesOptions(t *testing.T) {
+	setup(t)
+
+	Property(t, 2, func(r *rand.Rand) (interface{}, func(addr string)) {
+		n := r.Intn(100)
+		return n, func(addr string) {
+			go func() {
+				time.Sleep(Timeout + 50*time.Millisecond)
+				sendCounter(n, addr)
+			}()
+		}
+	}, checkCounter, WithLinger(Timeout+200*time.Millisecond))
+}
@@ -0,0 +1,397 @@
+// Package quic decodes the client Initial packets of QUIC version 1 (RFC 9000)
+// far enough to read the server name from the TLS ClientHello they carry.
+//
+// Initial packets are protected with keys derived from the client's chosen
+// destination connection ID and a published salt (RFC 9001, section 5.2), so
+// anyone observing them can decrypt them. Nothing beyond that is decoded.
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Version1 is the QUIC version number from RFC 9000.
+const Version1 = 0x00000001
+
+var initialSaltV1 = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+var (
+	errTruncated = errors.New("quic: packet truncated")
+	errFrame     = errors.New("quic: unexpected frame in Initial packet")
+)
+
+// Stats counts what a Decoder has seen.
+type Stats struct {
+	// Initial is the number of client Initial packets decrypted.
+	Initial int
+	// VersionNegotiation is the number of version negotiation datagrams.
+	VersionNegotiation int
+	// OtherQUIC is the number of version 1 long header packets that are not
+	// Initial packets (0-RTT, Handshake and Retry).
+	OtherQUIC int
+	// NonQUIC is the number of datagrams that don't start with a version 1
+	// long header. Short header packets can't be told apart from arbitrary
+	// data without connection state, so they are counted here too.
+	NonQUIC int
+	// Invalid is the number of Initial packets that failed to decrypt or
+	// parse.
+	Invalid int
+}
+
+// Decoder reassembles the CRYPTO stream of every client Initial it is given,
+// keyed by destination connection ID, so that a ClientHello spanning several
+// packets is still found.
+type Decoder struct {
+	Stats Stats
+
+	streams map[string]*cryptoStream
+	order   []string
+}
+
+type cryptoStream struct {
+	chunks map[uint64][]byte
+}
+
+// NewDecoder returns an empty Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{streams: map[string]*cryptoStream{}}
+}
+
+// Decode processes one UDP datagram, which may hold several coalesced QUIC
+// packets.
+func (d *Decoder) Decode(datagram []byte) {
+	if len(datagram) < 5 || datagram[0]&0x80 == 0 {
+		d.Stats.NonQUIC++
+		return
+	}
+	version := binary.BigEndian.Uint32(datagram[1:5])
+	if version == 0 {
+		d.Stats.VersionNegotiation++
+		return
+	}
+	if version != Version1 {
+		d.Stats.NonQUIC++
+		return
+	}
+
+	for len(datagram) > 0 && datagram[0]&0x80 != 0 {
+		packetType := (datagram[0] & 0x30) >> 4
+		dcid, payload, rest, err := openInitial(datagram)
+		if err != nil {
+			if packetType == 0 {
+				d.Stats.Invalid++
+			} else {
+				d.Stats.OtherQUIC++
+			}
+			if rest == nil {
+				return
+			}
+			datagram = rest
+			continue
+		}
+		if err := d.addFrames(string(dcid), payload); err != nil {
+			d.Stats.Invalid++
+		} else {
+			d.Stats.Initial++
+		}
+		datagram = rest
+	}
+}
+
+func (d *Decoder) addFrames(dcid string, payload []byte) error {
+	s, ok := d.streams[dcid]
+	if !ok {
+		s = &cryptoStream{chunks: map[uint64][]byte{}}
+		d.streams[dcid] = s
+		d.order = append(d.order, dcid)
+	}
+	for len(payload) > 0 {
+		frameType := payload[0]
+		payload = payload[1:]
+		switch frameType {
+		case 0x00, 0x01: // PADDING, PING
+		case 0x06: // CRYPTO
+			offset, n := readVarint(payload)
+			if n == 0 {
+				return errTruncated
+			}
+			payload = payload[n:]
+			length, n := readVarint(payload)
+			if n == 0 || uint64(len(payload)-n) < length {
+				return errTruncated
+			}
+			s.chunks[offset] = payload[n : n+int(length)]
+			payload = payload[n+int(length):]
+		default:
+			return errFrame
+		}
+	}
+	return nil
+}
+
+// ServerNames returns the server name from every complete ClientHello seen so
+// far, in the order their connection IDs were first seen. ClientHellos without
+// a server_name extension yield an empty string.
+func (d *Decoder) ServerNames() []string {
+	names := []string{}
+	for _, dcid := range d.order {
+		name, ok := parseClientHello(d.streams[dcid].contiguous())
+		if ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s *cryptoStream) contiguous() []byte {
+	var data []byte
+	for {
+		chunk, ok := s.chunks[uint64(len(data))]
+		if !ok || len(chunk) == 0 {
+			return data
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// openInitial removes the protection from the client Initial packet at the
+// start of b, returning its destination connection ID, its decrypted payload
+// and any packets coalesced after it. rest is nil when the packet boundaries
+// couldn't be determined.
+func openInitial(b []byte) (dcid, payload, rest []byte, err error) {
+	p := 5
+	if len(b) < p+1 {
+		return nil, nil, nil, errTruncated
+	}
+	dcidLen := int(b[p])
+	p++
+	if len(b) < p+dcidLen+1 {
+		return nil, nil, nil, errTruncated
+	}
+	dcid = b[p : p+dcidLen]
+	p += dcidLen
+	p += 1 + int(b[p]) // source connection ID
+
+	packetType := (b[0] & 0x30) >> 4
+	if packetType == 3 { // Retry packets have no length and end the datagram
+		return nil, nil, []byte{}, errors.New("quic: retry packet")
+	}
+	if packetType == 0 {
+		tokenLen, n := readVarint(safeSlice(b, p))
+		if n == 0 {
+			return nil, nil, nil, errTruncated
+		}
+		p += n + int(tokenLen)
+	}
+	length, n := readVarint(safeSlice(b, p))
+	if n == 0 {
+		return nil, nil, nil, errTruncated
+	}
+	pnOffset := p + n
+	end := pnOffset + int(length)
+	if end > len(b) || length < 20 {
+		return nil, nil, nil, errTruncated
+	}
+	rest = b[end:]
+	if packetType != 0 {
+		return nil, nil, rest, errors.New("quic: not an Initial packet")
+	}
+
+	key, iv, hp := clientInitialKeys(dcid)
+
+	// Work on a copy so the caller's capture is left untouched.
+	packet := append([]byte(nil), b[:end]...)
+	hpBlock, err := aes.NewCipher(hp)
+	if err != nil {
+		return nil, nil, rest, err
+	}
+	mask := make([]byte, aes.BlockSize)
+	hpBlock.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	pnLen := int(packet[0]&0x03) + 1
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(packet[pnOffset+i])
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, rest, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, rest, err
+	}
+	nonce := append([]byte(nil), iv...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * uint(i)))
+	}
+	header := packet[:pnOffset+pnLen]
+	payload, err = aead.Open(nil, nonce, packet[pnOffset+pnLen:], header)
+	if err != nil {
+		return nil, nil, rest, err
+	}
+	return dcid, payload, rest, nil
+}
+
+// clientInitialKeys derives the client's Initial packet protection keys for
+// the given destination connection ID (RFC 9001, section 5.2).
+func clientInitialKeys(dcid []byte) (key, iv, hp []byte) {
+	initialSecret := hkdfExtract(initialSaltV1, dcid)
+	secret := hkdfExpandLabel(initialSecret, "client in", sha256.Size)
+	key = hkdfExpandLabel(secret, "quic key", 16)
+	iv = hkdfExpandLabel(secret, "quic iv", 12)
+	hp = hkdfExpandLabel(secret, "quic hp", 16)
+	return key, iv, hp
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from TLS 1.3 with an empty
+// context, which is all QUIC Initial keys need.
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	fullLabel := "tls13 " + label
+	info := []byte{byte(length >> 8), byte(length), byte(len(fullLabel))}
+	info = append(info, fullLabel...)
+	info = append(info, 0)
+
+	var out, prev []byte
+	for i := byte(1); len(out) < length; i++ {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+// readVarint decodes a QUIC variable-length integer, returning the number of
+// bytes used, or 0 if b is too short.
+func readVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0
+	}
+	v := uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+func safeSlice(b []byte, from int) []byte {
+	if from > len(b) {
+		return nil
+	}
+	return b[from:]
+}
+
+// parseClientHello returns the server name from a TLS handshake message
+// holding a ClientHello. ok is false if the message is incomplete or isn't a
+// ClientHello.
+func parseClientHello(msg []byte) (serverName string, ok bool) {
+	if len(msg) < 4 || msg[0] != 1 {
+		return "", false
+	}
+	length := int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3])
+	if len(msg) < 4+length {
+		return "", false
+	}
+	r := reader(msg[4 : 4+length])
+	if !r.skip(2+32) || !r.skipVector(1) || !r.skipVector(2) || !r.skipVector(1) {
+		return "", false
+	}
+	extensions, ok := r.vector(2)
+	if !ok {
+		// A ClientHello may legally omit extensions entirely.
+		return "", len(r) == 0
+	}
+	for len(extensions) > 0 {
+		var extType uint16
+		var data reader
+		if extType, ok = extensions.uint16(); !ok {
+			return "", false
+		}
+		if data, ok = extensions.vector(2); !ok {
+			return "", false
+		}
+		if extType != 0 { // server_name
+			continue
+		}
+		list, ok := data.vector(2)
+		if !ok {
+			return "", false
+		}
+		for len(list) > 0 {
+			nameType := list[0]
+			list = list[1:]
+			name, ok := list.vector(2)
+			if !ok {
+				return "", false
+			}
+			if nameType == 0 { // host_name
+				return string(name), true
+			}
+		}
+	}
+	return "", true
+}
+
+type reader []byte
+
+func (r *reader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *reader) uint16() (uint16, bool) {
+	if len(*r) < 2 {
+		return 0, false
+	}
+	v := binary.BigEndian.Uint16(*r)
+	*r = (*r)[2:]
+	return v, true
+}
+
+func (r *reader) vector(lenBytes int) (reader, bool) {
+	if len(*r) < lenBytes {
+		return nil, false
+	}
+	n := 0
+	for _, b := range (*r)[:lenBytes] {
+		n = n<<8 | int(b)
+	}
+	*r = (*r)[lenBytes:]
+	if len(*r) < n {
+		return nil, false
+	}
+	v := (*r)[:n]
+	*r = (*r)[n:]
+	return v, true
+}
+
+func (r *reader) skipVector(lenBytes int) bool {
+	_, ok := r.vector(lenBytes)
+	return ok
+}
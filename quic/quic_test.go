@@ -0,0 +1,132 @@
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+func TestClientInitialKeys(t *testing.T) {
+	// Test vectors from RFC 9001, appendix A.1.
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	key, iv, hp := clientInitialKeys(dcid)
+
+	expected := map[string][]byte{
+		"1f369613dd76d5467730efcbe3b1a22d": key,
+		"fa044b2f42a3fd3b46fb255c":         iv,
+		"9f50449e04a0e810283a1e9933adedd2": hp,
+	}
+	for want, got := range expected {
+		if hex.EncodeToString(got) != want {
+			t.Errorf("Expected %s but got %x", want, got)
+		}
+	}
+}
+
+func varint(v int) []byte {
+	return []byte{0x40 | byte(v>>8), byte(v)}
+}
+
+// sealInitial builds a protected client Initial packet carrying one CRYPTO
+// frame, padded to the minimum size clients must use.
+func sealInitial(dcid []byte, pn uint16, offset int, data []byte) []byte {
+	payload := append([]byte{0x06}, varint(offset)...)
+	payload = append(payload, varint(len(data))...)
+	payload = append(payload, data...)
+	for len(payload) < 1100 {
+		payload = append(payload, 0)
+	}
+
+	const pnLen = 2
+	header := []byte{0xc0 | (pnLen - 1), 0, 0, 0, 1, byte(len(dcid))}
+	header = append(header, dcid...)
+	header = append(header, 0, 0) // empty source connection ID and token
+	header = append(header, varint(pnLen+len(payload)+16)...)
+	pnOffset := len(header)
+	header = append(header, byte(pn>>8), byte(pn))
+
+	key, iv, hp := clientInitialKeys(dcid)
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := append([]byte(nil), iv...)
+	nonce[len(nonce)-2] ^= byte(pn >> 8)
+	nonce[len(nonce)-1] ^= byte(pn)
+	packet := aead.Seal(append([]byte(nil), header...), nonce, payload, header)
+
+	hpBlock, _ := aes.NewCipher(hp)
+	mask := make([]byte, aes.BlockSize)
+	hpBlock.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	for i := 0; i < pnLen; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+	}
+	return packet
+}
+
+// clientHello builds a minimal TLS ClientHello handshake message with a
+// server_name extension.
+func clientHello(serverName string) []byte {
+	name := append([]byte{0, byte(len(serverName) >> 8), byte(len(serverName))}, serverName...)
+	list := append([]byte{byte(len(name) >> 8), byte(len(name))}, name...)
+	ext := append([]byte{0, 0, byte(len(list) >> 8), byte(len(list))}, list...)
+
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0)                   // session ID
+	body = append(body, 0, 2, 0x13, 0x01)    // cipher suites
+	body = append(body, 1, 0)                // compression methods
+	body = append(body, byte(len(ext)>>8), byte(len(ext)))
+	body = append(body, ext...)
+	return append([]byte{1, 0, byte(len(body) >> 8), byte(len(body))}, body...)
+}
+
+func TestDecodeServerName(t *testing.T) {
+	dcid := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	d := NewDecoder()
+	d.Decode(sealInitial(dcid, 0, 0, clientHello("telemetry.example.com")))
+
+	names := d.ServerNames()
+	if len(names) != 1 || names[0] != "telemetry.example.com" {
+		t.Errorf("Expected the ClientHello server name, got %#v", names)
+	}
+	if d.Stats.Initial != 1 {
+		t.Errorf("Expected 1 Initial packet, got %#v", d.Stats)
+	}
+}
+
+func TestDecodeSplitClientHello(t *testing.T) {
+	dcid := []byte{9, 9, 9, 9}
+	hello := clientHello("split.example.com")
+	d := NewDecoder()
+
+	// Deliver the second half first to check reassembly by offset.
+	d.Decode(sealInitial(dcid, 1, 20, hello[20:]))
+	if names := d.ServerNames(); len(names) != 0 {
+		t.Errorf("Expected no complete ClientHello yet, got %#v", names)
+	}
+	d.Decode(sealInitial(dcid, 0, 0, hello[:20]))
+
+	names := d.ServerNames()
+	if len(names) != 1 || names[0] != "split.example.com" {
+		t.Errorf("Expected the reassembled server name, got %#v", names)
+	}
+}
+
+func TestDecodeSkipsOtherTraffic(t *testing.T) {
+	d := NewDecoder()
+	d.Decode([]byte("mystat:1|c"))
+	d.Decode([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+
+	corrupt := sealInitial([]byte{1}, 0, 0, clientHello("a"))
+	corrupt[len(corrupt)-1] ^= 0xff
+	d.Decode(corrupt)
+
+	expected := Stats{VersionNegotiation: 1, NonQUIC: 1, Invalid: 1}
+	if d.Stats != expected {
+		t.Errorf("Expected %#v but got %#v", expected, d.Stats)
+	}
+	if names := d.ServerNames(); len(names) != 0 {
+		t.Errorf("Expected no server names, got %#v", names)
+	}
+}
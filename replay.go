@@ -0,0 +1,87 @@
+package udp
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// derive returns a copy of the capture, with transform added to its
+// Transforms and the given packets.
+func (c *Capture) derive(transform string, packets []Packet) *Capture {
+	transforms := append(append([]string(nil), c.Transforms...), transform)
+	return &Capture{Packets: packets, Transforms: transforms, partitionBy: c.partitionBy}
+}
+
+// describe names the capture for failure messages, marking it if it is
+// derived.
+func (c *Capture) describe() string {
+	if len(c.Transforms) == 0 {
+		return "capture"
+	}
+	return fmt.Sprintf("transformed capture (%s)", strings.Join(c.Transforms, ", "))
+}
+
+// Shuffled returns a copy of the capture with its packets in a random order,
+// the same for the same seed. The packets take the times of the packets whose
+// places they take, so a replay keeps the original timing.
+func (c *Capture) Shuffled(seed int64) *Capture {
+	packets := make([]Packet, len(c.Packets))
+	for i, j := range rand.New(rand.NewSource(seed)).Perm(len(c.Packets)) {
+		packets[i] = c.Packets[j]
+		packets[i].Time = c.Packets[i].Time
+	}
+	return c.derive(fmt.Sprintf("shuffled with seed %d", seed), packets)
+}
+
+// Delayed returns a copy of the capture with the time of each packet i put
+// back by perPacket(i), so that Replay sends it that much later.
+func (c *Capture) Delayed(perPacket func(i int) time.Duration) *Capture {
+	packets := append([]Packet(nil), c.Packets...)
+	for i := range packets {
+		packets[i].Time = packets[i].Time.Add(perPacket(i))
+	}
+	return c.derive("delayed", packets)
+}
+
+// Dropped returns a copy of the capture without the packets at the given
+// indices. Indices out of range are ignored.
+func (c *Capture) Dropped(indices ...int) *Capture {
+	drop := map[int]bool{}
+	for _, i := range indices {
+		drop[i] = true
+	}
+	packets := []Packet{}
+	for i, p := range c.Packets {
+		if !drop[i] {
+			packets = append(packets, p)
+		}
+	}
+	sorted := append([]int(nil), indices...)
+	sort.Ints(sorted)
+	return c.derive(fmt.Sprintf("dropped %v", sorted), packets)
+}
+
+// Replay writes the payload of every packet in the capture to w, one Write
+// per packet and in order, keeping the gaps between their times: a packet is
+// written no sooner after the first than it was received after it. Errors
+// name the capture as transformed if it was derived by Shuffled, Delayed or
+// Dropped.
+func (c *Capture) Replay(w io.Writer) error {
+	if len(c.Packets) == 0 {
+		return nil
+	}
+	started, first := time.Now(), c.Packets[0].Time
+	for i, p := range c.Packets {
+		if wait := p.Time.Sub(first) - time.Since(started); wait > 0 {
+			time.Sleep(wait)
+		}
+		if _, err := w.Write(p.Payload); err != nil {
+			return fmt.Errorf("replaying packet %d of %s: %v", i, c.describe(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package udp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCapture(payloads ...string) *Capture {
+	start := time.Now()
+	c := &Capture{}
+	for i, p := range payloads {
+		c.Packets = append(c.Packets, Packet{Payload: []byte(p), Time: start.Add(time.Duration(i) * time.Millisecond)})
+	}
+	return c
+}
+
+func TestCaptureTransforms(t *testing.T) {
+	c := testCapture("a", "b", "c", "d")
+
+	shuffled := c.Shuffled(3)
+	if !reflect.DeepEqual(shuffled.Payloads(), c.Shuffled(3).Payloads()) || reflect.DeepEqual(shuffled.Payloads(), c.Payloads()) {
+		t.Errorf("Expected the same seed to give the same new order, got %q", shuffled.Payloads())
+	}
+	for i := range shuffled.Packets {
+		if !shuffled.Packets[i].Time.Equal(c.Packets[i].Time) {
+			t.Errorf("Expected packet %d to keep the time of its place", i)
+		}
+	}
+
+	dropped := shuffled.Dropped(3, 0)
+	if len(dropped.Packets) != 2 || !reflect.DeepEqual(dropped.Transforms, []string{"shuffled with seed 3", "dropped [0 3]"}) {
+		t.Errorf("Expected two packets and both transforms, got %d and %q", len(dropped.Packets), dropped.Transforms)
+	}
+	if len(c.Transforms) != 0 || len(c.Packets) != 4 {
+		t.Errorf("Expected the original capture to be left as it was")
+	}
+
+	delayed := c.Delayed(func(i int) time.Duration { return time.Duration(i) * time.Second })
+	if d := delayed.Packets[2].Time.Sub(c.Packets[2].Time); d != 2*time.Second {
+		t.Errorf("Expected packet 2 to be put back 2s, got %v", d)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestCaptureReplay(t *testing.T) {
+	udpClient := setup(t)
+	c := testCapture("a", "b", "c").Delayed(func(i int) time.Duration {
+		return time.Duration(i) * 10 * time.Millisecond
+	})
+
+	started := time.Now()
+	ShouldReceiveOnlyPackets(t, []string{"a", "c"}, func() {
+		if err := c.Dropped(1).Replay(udpClient); err != nil {
+			t.Error(err)
+		}
+	})
+	if d := time.Since(started); d < 22*time.Millisecond {
+		t.Errorf("Expected the replay to keep the delays, took %v", d)
+	}
+
+	err := c.Shuffled(2).Replay(failingWriter{})
+	if err == nil || !strings.Contains(err.Error(), "replaying packet 0 of transformed capture (delayed, shuffled with seed 2): broken pipe") {
+		t.Errorf("Expected the error to name the transforms, got %v", err)
+	}
+}
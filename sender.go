@@ -0,0 +1,151 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+// sentPacket is a send made through a Client that no capture has covered yet.
+type sentPacket struct {
+	payload string
+	at      time.Time
+	caller  string
+}
+
+// Client sends packets to a server. Sends are recorded so that a packet
+// written before an assertion starts listening, which races the listener and
+// makes tests flaky, is reported instead of silently lost.
+type Client struct {
+	conn   net.Conn
+	server *Server
+	tagged bool
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithCaptureToken tags every packet the client sends with a token unique to
+// the capture listening at the time. Captures strip the token before looking
+// at the packet, and WithExclusiveUse relies on it to tell the test's own
+// packets from anyone else's.
+func WithCaptureToken() ClientOption {
+	return func(c *Client) {
+		c.tagged = true
+	}
+}
+
+// NewClient returns a Client sending to the server's address.
+func (s *Server) NewClient(t TestingT, opts ...ClientOption) *Client {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	conn, err := net.Dial("udp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	c := &Client{conn: conn, server: s}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewClient returns a Client sending to the address set with SetAddr.
+func NewClient(t TestingT, opts ...ClientOption) *Client {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.NewClient(t, opts...)
+}
+
+// Send sends the payload as a single datagram.
+func (c *Client) Send(payload string) error {
+	_, file, line, _ := runtime.Caller(1)
+	s := c.server
+	s.sendsMu.Lock()
+	s.sends = append(s.sends, sentPacket{payload, time.Now(), fmt.Sprintf("%s:%d", file, line)})
+	token := s.token
+	s.sendsMu.Unlock()
+	data := []byte(payload)
+	if c.tagged && token != "" {
+		data = append([]byte(tokenPrefix+token+tokenEnd), data...)
+	}
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// Close closes the client's socket.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// WithStrictWindowing fails the assertion, rather than logging a warning, when
+// a packet was sent through a Client before it started listening. Packets sent
+// to a server that keeps its socket bound are buffered and read, so they never
+// fail it.
+func WithStrictWindowing() Option {
+	return func(c *callConfig) {
+		c.strictWindowing = true
+	}
+}
+
+func (s *Server) takeSends() []sentPacket {
+	s.sendsMu.Lock()
+	defer s.sendsMu.Unlock()
+	packets := s.sends
+	s.sends = nil
+	return packets
+}
+
+func orphanMessage(p sentPacket) string {
+	return fmt.Sprintf("Packet %#v was sent at %s from %s while nothing was listening; move the send inside the assertion body",
+		p.payload, p.at.Format("15:04:05.000000"), p.caller)
+}
+
+// openWindow reports the sends no capture could have seen, before a capture
+// starts: as failures WithStrictWindowing, and otherwise as warnings. Sends to
+// a server that keeps its socket bound are read by the capture, so they are
+// not reported.
+func (c *call) openWindow(t TestingT, cfg *callConfig) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s := c.s
+	token := newToken()
+	s.sendsMu.Lock()
+	s.token = token
+	s.sendsMu.Unlock()
+	orphans := s.takeSends()
+	if s.conn != nil || len(orphans) == 0 {
+		return
+	}
+	if cfg.strictWindowing {
+		c.printLocation(t)
+		c.reportOrphanedSends(orphans)
+		return
+	}
+	if l, ok := t.(logger); ok {
+		for _, p := range orphans {
+			l.Logf("udp: warning: %s", orphanMessage(p))
+		}
+	}
+}
+
+// closeWindow forgets the sends made while a capture was listening.
+func (s *Server) closeWindow() {
+	s.sendsMu.Lock()
+	s.token = ""
+	s.sendsMu.Unlock()
+	s.takeSends()
+}
+
+// reportOrphanedSends adds a failure line for every send no capture could
+// have seen.
+func (c *call) reportOrphanedSends(orphans []sentPacket) {
+	for _, p := range orphans {
+		c.errorF("%s", orphanMessage(p))
+	}
+}
@@ -0,0 +1,82 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientSendInsideBody(t *testing.T) {
+	setup(t)
+	client := NewClient(t)
+	defer client.Close()
+
+	ft := &fakeT{}
+	ShouldReceiveOnly(ft, "foo", func() {
+		client.Send("foo")
+	})
+	if len(ft.errors) != 0 || len(ft.logs) != 0 {
+		t.Errorf("Expected no errors or warnings, got %#v %#v", ft.errors, ft.logs)
+	}
+}
+
+func TestOrphanedSendWarns(t *testing.T) {
+	// SetAddr(":0") keeps its socket bound, so nothing would be orphaned.
+	SetAddr(freeAddrs(t, 1)[0])
+	client := NewClient(t)
+	defer client.Close()
+
+	ft := &fakeT{}
+	client.Send("early")
+	ShouldReceiveNothing(ft, func() {})
+	if len(ft.errors) != 0 || len(ft.logs) != 1 || !strings.Contains(ft.logs[0], `"early"`) ||
+		!strings.Contains(ft.logs[0], "sender_test.go") {
+		t.Errorf("Expected a warning about the early send, got %#v %#v", ft.errors, ft.logs)
+	}
+}
+
+func TestOrphanedSendFailsWithStrictWindowing(t *testing.T) {
+	SetAddr(freeAddrs(t, 1)[0])
+	client := NewClient(t)
+	defer client.Close()
+
+	ft := &fakeT{}
+	client.Send("1500000000 early 1")
+	ShouldReceiveFieldsInOrder(ft, []string{`\d+`, `\w+`}, func() {
+		client.Send("1500000000 late")
+	}, WithStrictWindowing())
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "early") || len(ft.logs) != 0 {
+		t.Errorf("Expected the early send to fail the assertion, got %#v %#v", ft.errors, ft.logs)
+	}
+}
+
+func TestStrictWindowingOnEveryAssertion(t *testing.T) {
+	SetAddr(freeAddrs(t, 1)[0])
+	client := NewClient(t)
+	defer client.Close()
+
+	ft := &fakeT{}
+	client.Send("early")
+	ShouldReceive(ft, "late", func() {
+		client.Send("late")
+	}, WithStrictWindowing())
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], `"early"`) || len(ft.logs) != 0 {
+		t.Errorf("Expected the early send to fail the assertion, got %#v %#v", ft.errors, ft.logs)
+	}
+}
+
+func TestBoundSocketSendIsNotOrphaned(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := s.NewClient(t)
+	defer client.Close()
+
+	ft := &fakeT{}
+	client.Send("early")
+	s.ShouldReceiveOnly(ft, "early", func() {}, WithStrictWindowing())
+	if len(ft.errors) != 0 || len(ft.logs) != 0 {
+		t.Errorf("Expected a send buffered by the bound socket to be read, got %#v %#v", ft.errors, ft.logs)
+	}
+}
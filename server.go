@@ -0,0 +1,192 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server listens for the packets its assertions capture, with its own socket
+// and timeout, so that several can be used side by side, including from
+// parallel tests. The package level assertions use a default server, which
+// binds the address given to SetAddr afresh for every assertion, unless Listen
+// keeps it bound; tests calling t.Parallel must each listen on their own
+// address.
+type Server struct {
+	// Timeout is how long the server's assertions wait for another packet.
+	// Zero means the package level Timeout.
+	Timeout time.Duration
+
+	addr *string
+	// conn is the socket a server made with NewServer keeps bound for its
+	// lifetime. It is nil for the default server, unless bound by Listen or
+	// SetAddr(":0").
+	conn     *net.UDPConn
+	sockOpts []sockOpt
+
+	// sends holds the packets sent through the server's Clients since the
+	// last capture window opened or closed. Any still there when a window
+	// opens were sent while nothing was listening.
+	sendsMu sync.Mutex
+	sends   []sentPacket
+	// token is unique to the capture listening now, if any. Clients made
+	// WithCaptureToken tag their packets with it.
+	token string
+}
+
+var defaultServer = &Server{}
+
+// call is the state of a single assertion: the socket its capture reads and
+// the failure message it builds, which no other assertion can see.
+type call struct {
+	s        *Server
+	listener *net.UDPConn
+
+	logMu  sync.Mutex
+	logBuf []logLine
+	// events is where the assertion logs its lifecycle, if anywhere.
+	events eventSink
+	// located is set once the failure message starts with the location.
+	located bool
+	// foreign holds the packets WithExclusiveUse found not to be the
+	// test's own.
+	foreign []Packet
+	// fatal reports the failure with Fatal rather than Error.
+	fatal bool
+}
+
+func (s *Server) newCall() *call {
+	return &call{s: s}
+}
+
+// NewServer binds addr and returns a Server listening on it. Unlike the
+// default server its socket stays bound until Close, so packets sent between
+// two of its assertions are read by the second.
+func NewServer(addr string) (*Server, error) {
+	resAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", resAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{addr: &addr, conn: conn}, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	if s.conn != nil {
+		return s.conn.LocalAddr().String()
+	}
+	if s.addr == nil {
+		return ""
+	}
+	return *s.addr
+}
+
+// Close releases the server's socket.
+func (s *Server) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// Listen binds the server's address until the returned function is called,
+// so that the assertions in between share one socket instead of binding it
+// afresh each time, and packets sent between two of them are read by the
+// second. Call the returned function with defer or t.Cleanup. A server whose
+// socket is already bound is left as it is, and the returned function does
+// nothing.
+func (s *Server) Listen(t TestingT) func() {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if s.conn != nil {
+		return func() {}
+	}
+	if s.addr == nil {
+		t.Fatal("udp: SetAddr must be called before Listen")
+		return func() {}
+	}
+	resAddr, err := net.ResolveUDPAddr("udp", *s.addr)
+	if err != nil {
+		t.Fatal(err)
+		return func() {}
+	}
+	conn, err := net.ListenUDP("udp", resAddr)
+	if err != nil {
+		t.Fatal(err)
+		return func() {}
+	}
+	s.conn = conn
+	return func() {
+		if h, ok := t.(HelperT); ok {
+			h.Helper()
+		}
+		if s.conn != conn {
+			return
+		}
+		s.conn = nil
+		if err := conn.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// Listen calls Server.Listen on the default server.
+func Listen(t TestingT) func() {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.Listen(t)
+}
+
+// eventSink receives the lifecycle events of a capture as key and value pairs.
+// WithSlog sets one on Go versions that have log/slog.
+type eventSink interface {
+	event(name string, args ...interface{})
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Timeout == 0 {
+		return Timeout
+	}
+	return s.Timeout
+}
+
+func (c *call) errorF(format string, args ...interface{}) {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	c.logBuf = append(c.logBuf, logLine{format, args})
+}
+
+func (c *call) emitLog(t TestingT) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c.logMu.Lock()
+	buf := c.logBuf
+	c.logBuf = []logLine{}
+	events := c.events
+	c.events = nil
+	c.logMu.Unlock()
+
+	if len(buf) > 0 && events != nil {
+		events.event("assertion_failed", "kind", assertionName(), "location", callerLocation())
+	}
+	if len(buf) > 0 {
+		lines := make([]string, len(buf))
+		for i, l := range buf {
+			lines[i] = fmt.Sprintf(l.format, l.args...)
+		}
+		if c.fatal {
+			t.Fatal(strings.Join(lines, "\n"))
+		} else {
+			t.Error(strings.Join(lines, "\n"))
+		}
+	}
+}
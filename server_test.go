@@ -0,0 +1,101 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestTwoServers(t *testing.T) {
+	servers := make([]*Server, 2)
+	clients := make([]net.Conn, 2)
+	for i := range servers {
+		s, err := NewServer("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		servers[i] = s
+		conn, err := net.Dial("udp", s.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		clients[i] = conn
+	}
+
+	servers[0].ShouldReceiveOnly(t, "foo", func() {
+		clients[0].Write([]byte("foo"))
+		clients[1].Write([]byte("bar"))
+	})
+	servers[1].ShouldReceiveOnly(t, "bar", func() {})
+
+	ft := &fakeT{}
+	servers[0].ShouldReceiveAll(ft, []string{"baz"}, func() {
+		clients[1].Write([]byte("baz"))
+	})
+	servers[1].ShouldReceive(t, "baz", func() {})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], `Expected to find: "baz"`) {
+		t.Errorf("Expected only the first server to fail, got %#v", ft.errors)
+	}
+}
+
+func TestParallelServers(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			s, err := NewServer("127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			a := s.Addr()
+			client := s.NewClient(t)
+			defer client.Close()
+
+			for i := 0; i < 20; i++ {
+				s.ShouldReceiveOnly(t, a, func() {
+					client.Send(a)
+				})
+			}
+
+			// Each failure message must hold only this test's lines.
+			ft := &fakeT{}
+			s.ShouldReceiveOnly(ft, "foo", func() {
+				client.Send(a)
+			})
+			if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], fmt.Sprintf("Expected: \"foo\"\nBut got: %#v", a)) {
+				t.Errorf("Expected this server's failure alone, got %#v", ft.errors)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	a := freeAddrs(t, 1)[0]
+	s := &Server{addr: &a}
+	conn, err := net.Dial("udp", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	teardown := s.Listen(t)
+	s.ShouldReceiveOnly(t, "foo", func() {
+		conn.Write([]byte("foo"))
+	})
+	conn.Write([]byte("bar"))
+	s.ShouldReceiveOnly(t, "bar", func() {})
+	teardown()
+	if s.conn != nil {
+		t.Errorf("Expected teardown to release the socket")
+	}
+	teardown()
+
+	ft := &fakeT{}
+	(&Server{}).Listen(ft)()
+	if len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "SetAddr must be called before Listen") {
+		t.Errorf("Expected Listen without an address to be fatal, got %#v", ft.fatals)
+	}
+}
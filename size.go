@@ -0,0 +1,194 @@
+package udp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	defaultMinSamples = 20
+
+	// maxOffenders bounds how many oversized packets a failure lists.
+	maxOffenders = 5
+	// maxPreviewSize bounds how much of a packet a failure quotes.
+	maxPreviewSize = 32
+)
+
+// WithMinSamples sets how many packets ShouldHavePacketSizePercentileBelow
+// needs before it trusts the percentile. It defaults to 20.
+func WithMinSamples(n int) Option {
+	return func(c *callConfig) {
+		c.minSamples = n
+	}
+}
+
+// Stats summarizes the sizes of the packets of a capture.
+type Stats struct {
+	// Count is the number of packets.
+	Count int
+	// Bytes is the total size of their payloads.
+	Bytes int
+
+	sizes []int
+}
+
+// Stats returns the size statistics of the captured packets.
+func (c *Capture) Stats() *Stats {
+	s := &Stats{Count: len(c.Packets), sizes: make([]int, 0, len(c.Packets))}
+	for _, p := range c.Packets {
+		s.Bytes += len(p.Payload)
+		s.sizes = append(s.sizes, len(p.Payload))
+	}
+	sort.Ints(s.sizes)
+	return s
+}
+
+// SizePercentile returns the payload size, in bytes, that p percent of the
+// packets are no larger than, by the nearest rank method. It returns 0 for an
+// empty capture.
+func (s *Stats) SizePercentile(p float64) int {
+	if len(s.sizes) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(s.sizes))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(s.sizes) {
+		rank = len(s.sizes)
+	}
+	return s.sizes[rank-1]
+}
+
+// ShouldHavePacketSizePercentileBelow will fire a test error if the given
+// percentile, from 0 to 100, of the sizes of the packets the given function
+// sends exceeds maxBytes, listing the largest packets that do. It also fails
+// if fewer packets than the minimum set with WithMinSamples are received, as
+// the percentile of a handful of packets means little.
+func (s *Server) ShouldHavePacketSizePercentileBelow(t TestingT, percentile float64, maxBytes int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	if cfg.minSamples == 0 {
+		cfg.minSamples = defaultMinSamples
+	}
+	if percentile < 0 || percentile > 100 {
+		t.Fatal(fmt.Sprintf("udp: percentile %v out of range at %s", percentile, callerLocation()))
+		return
+	}
+	capture := &Capture{Packets: c.capturePackets(t, body, cfg)}
+	stats := capture.Stats()
+	if stats.Count < cfg.minSamples {
+		c.printLocation(t)
+		c.errorF("Expected at least %d packets to compute the p%v size, but got insufficient samples: %d", cfg.minSamples, percentile, stats.Count)
+		return
+	}
+	got := stats.SizePercentile(percentile)
+	if got <= maxBytes {
+		return
+	}
+
+	offenders := []int{}
+	for i, p := range capture.Packets {
+		if len(p.Payload) > maxBytes {
+			offenders = append(offenders, i)
+		}
+	}
+	sort.SliceStable(offenders, func(i, j int) bool {
+		return len(capture.Packets[offenders[i]].Payload) > len(capture.Packets[offenders[j]].Payload)
+	})
+	c.printLocation(t)
+	c.errorF("Expected the p%v packet size to be at most %d bytes, but it is %d bytes over %d packets", percentile, maxBytes, got, stats.Count)
+	c.errorF("Largest packets over %d bytes:", maxBytes)
+	for n, i := range offenders {
+		if n == maxOffenders {
+			c.errorF("... and %d more", len(offenders)-n)
+			break
+		}
+		payload := capture.Packets[i].Payload
+		if len(payload) > maxPreviewSize {
+			payload = payload[:maxPreviewSize]
+		}
+		c.errorF("%d: %d bytes %q", i, len(capture.Packets[i].Payload), payload)
+	}
+}
+
+// ShouldHavePacketSizePercentileBelow calls
+// Server.ShouldHavePacketSizePercentileBelow on the default server.
+func ShouldHavePacketSizePercentileBelow(t TestingT, percentile float64, maxBytes int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldHavePacketSizePercentileBelow(t, percentile, maxBytes, body, opts...)
+}
+
+// ShouldReceivePacketSizeExact will fire a test error unless the given
+// function sends at least index+1 datagrams over UDP, and the one at index,
+// counting from 0, is exactly size bytes.
+func (s *Server) ShouldReceivePacketSizeExact(t TestingT, index int, size int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkPacketSize(t, index, body, opts, fmt.Sprintf("%d", size), func(got int) bool { return got == size })
+}
+
+// ShouldReceivePacketSizeExact calls Server.ShouldReceivePacketSizeExact on the
+// default server.
+func ShouldReceivePacketSizeExact(t TestingT, index int, size int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceivePacketSizeExact(t, index, size, body, opts...)
+}
+
+// ShouldReceivePacketSizeRange will fire a test error unless the given
+// function sends at least index+1 datagrams over UDP, and the one at index,
+// counting from 0, is from min to max bytes.
+func (s *Server) ShouldReceivePacketSizeRange(t TestingT, index int, min, max int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkPacketSize(t, index, body, opts, fmt.Sprintf("between %d and %d", min, max), func(got int) bool { return got >= min && got <= max })
+}
+
+// ShouldReceivePacketSizeRange calls Server.ShouldReceivePacketSizeRange on the
+// default server.
+func ShouldReceivePacketSizeRange(t TestingT, index int, min, max int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceivePacketSizeRange(t, index, min, max, body, opts...)
+}
+
+// checkPacketSize fires a test error unless the given function sends a
+// datagram at index, and accept accepts its size. want describes the accepted
+// sizes in the failure message.
+func (s *Server) checkPacketSize(t TestingT, index int, body fn, opts []Option, want string, accept func(got int) bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if index < 0 {
+		t.Fatal(fmt.Sprintf("udp: negative packet index %d at %s", index, callerLocation()))
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	if index >= len(packets) {
+		c.printLocation(t)
+		c.errorF("Expected packet %d to be %s bytes, but got only %d packets", index, want, len(packets))
+		return
+	}
+	if got := len(packets[index].Payload); !accept(got) {
+		payload := packets[index].Payload
+		if len(payload) > maxPreviewSize {
+			payload = payload[:maxPreviewSize]
+		}
+		c.printLocation(t)
+		c.errorF("Expected packet %d to be %s bytes, but it is %d bytes %q", index, want, got, payload)
+	}
+}
@@ -0,0 +1,73 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizePercentile(t *testing.T) {
+	capture := &Capture{}
+	for i := 1; i <= 100; i++ {
+		capture.Packets = append(capture.Packets, Packet{Payload: make([]byte, i)})
+	}
+	stats := capture.Stats()
+	if stats.Count != 100 || stats.Bytes != 5050 {
+		t.Errorf("Expected 100 packets of 5050 bytes, got %d of %d", stats.Count, stats.Bytes)
+	}
+	for p, expected := range map[float64]int{0: 1, 50: 50, 99: 99, 100: 100} {
+		if got := stats.SizePercentile(p); got != expected {
+			t.Errorf("Expected p%v to be %d, got %d", p, expected, got)
+		}
+	}
+	if got := (&Capture{}).Stats().SizePercentile(99); got != 0 {
+		t.Errorf("Expected 0 for an empty capture, got %d", got)
+	}
+}
+
+func TestShouldHavePacketSizePercentileBelow(t *testing.T) {
+	udpClient := setup(t)
+
+	send := func(n int) func() {
+		return func() {
+			for i := 0; i < n; i++ {
+				udpClient.Write([]byte("small"))
+			}
+			udpClient.Write([]byte(strings.Repeat("x", 600)))
+		}
+	}
+	ShouldHavePacketSizePercentileBelow(t, 90, 512, send(9), WithMinSamples(10))
+
+	ft := &fakeT{}
+	ShouldHavePacketSizePercentileBelow(ft, 99, 512, send(9), WithMinSamples(10))
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "p99 packet size to be at most 512 bytes, but it is 600 bytes") ||
+		!strings.Contains(ft.errors[0], "\n9: 600 bytes \"xxxx") {
+		t.Errorf("Expected the percentile and the oversized packet to be reported, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldHavePacketSizePercentileBelow(ft, 99, 512, send(2))
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "insufficient samples: 3") {
+		t.Errorf("Expected too few packets to fail, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceivePacketSizeExact(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("barbaz"))
+	}
+
+	ShouldReceivePacketSizeExact(t, 1, 6, send)
+	ShouldReceivePacketSizeRange(t, 0, 1, 3, send)
+
+	ft := &fakeT{}
+	ShouldReceivePacketSizeExact(ft, 0, 4, send)
+	ShouldReceivePacketSizeExact(ft, 2, 4, send)
+	ShouldReceivePacketSizeRange(ft, 1, 1, 5, send)
+	if len(ft.errors) != 3 || !strings.HasSuffix(ft.errors[0], "Expected packet 0 to be 4 bytes, but it is 3 bytes \"foo\"") ||
+		!strings.HasSuffix(ft.errors[1], "Expected packet 2 to be 4 bytes, but got only 2 packets") ||
+		!strings.HasSuffix(ft.errors[2], "Expected packet 1 to be between 1 and 5 bytes, but it is 6 bytes \"barbaz\"") {
+		t.Errorf("Expected the packet sizes to be reported, got %#v", ft.errors)
+	}
+}
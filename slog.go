@@ -0,0 +1,25 @@
+//go:build go1.21
+// +build go1.21
+
+package udp
+
+import "log/slog"
+
+type slogSink struct {
+	logger *slog.Logger
+}
+
+func (s slogSink) event(name string, args ...interface{}) {
+	s.logger.Info(name, args...)
+}
+
+// WithSlog logs the lifecycle of the assertion's capture to logger as
+// structured records: listener_bound for each socket read from,
+// packet_received with the size and source of each packet, capture_closed
+// with how many packets and bytes were read, and assertion_failed with the
+// kind of assertion if it fails. Payloads are never logged.
+func WithSlog(logger *slog.Logger) Option {
+	return func(c *callConfig) {
+		c.events = slogSink{logger}
+	}
+}
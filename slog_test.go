@@ -0,0 +1,43 @@
+//go:build go1.21
+// +build go1.21
+
+package udp
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWithSlog(t *testing.T) {
+	udpClient := setup(t)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" || a.Key == "src" || a.Key == "addr" || a.Key == "location" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	ShouldReceive(t, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	}, WithSlog(logger))
+	ShouldReceive(&fakeT{}, "foo", func() {}, WithSlog(logger))
+
+	expected := []string{
+		"level=INFO msg=listener_bound listener=0",
+		"level=INFO msg=packet_received listener=0 size=3",
+		"level=INFO msg=capture_closed packets=1 bytes=3",
+		"level=INFO msg=listener_bound listener=0",
+		"level=INFO msg=capture_closed packets=0 bytes=0",
+		"level=INFO msg=assertion_failed kind=ShouldReceive",
+	}
+	got := strings.Split(strings.TrimSpace(regexp.MustCompile(` +\n`).ReplaceAllString(buf.String(), "\n")), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected records:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+}
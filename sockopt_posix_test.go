@@ -0,0 +1,69 @@
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestSetSockOpt(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.SetSockOpt(syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSockOpt(-1, -1, 1); err == nil {
+		t.Errorf("Expected an invalid option on a bound socket to be reported straight away")
+	}
+
+	got := -1
+	s.ShouldReceiveNothing(t, func() {
+		raw, err := s.conn.SyscallConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw.Control(func(fd uintptr) {
+			got, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got == 0 || got == -1 {
+		t.Errorf("Expected SO_BROADCAST to be set on the listener, got %d", got)
+	}
+}
+
+func TestSetSockOptFailure(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	laddr := conn.LocalAddr().(*net.UDPAddr)
+	addr := laddr.String()
+	conn.Close()
+
+	s := &Server{addr: &addr}
+	if err := s.SetSockOpt(-1, -1, 1); err != nil {
+		t.Fatalf("Expected the option to be queued until the listener is bound, got %v", err)
+	}
+	ft := &fakeT{}
+	ran := false
+	s.ShouldReceiveNothing(ft, func() { ran = true })
+	if ran || len(ft.fatals) != 1 {
+		t.Errorf("Expected the assertion to stop on the failing option, got ran=%v and %q", ran, ft.fatals)
+	}
+
+	conn, err = net.ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatalf("Expected the listener to be closed, got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,17 @@
+//go:build js || wasip1
+// +build js wasip1
+
+package udp
+
+import (
+	"errors"
+	"net"
+)
+
+func setSockOpt(conn *net.UDPConn, level, optname, optval int) error {
+	return errors.New("udp: socket options are not supported on wasm")
+}
+
+func readBufferSize(conn *net.UDPConn) (int, error) {
+	return 0, errors.New("udp: socket options are not supported on wasm")
+}
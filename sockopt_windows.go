@@ -0,0 +1,38 @@
+package udp
+
+import (
+	"net"
+	"syscall"
+)
+
+func setSockOpt(conn *net.UDPConn, level, optname, optval int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(syscall.Handle(fd), level, optname, optval)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// readBufferSize returns the SO_RCVBUF the system granted conn.
+func readBufferSize(conn *net.UDPConn) (int, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		size, sockErr = syscall.GetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, sockErr
+}
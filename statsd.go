@@ -0,0 +1,112 @@
+package udp
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultQuietGap is how long the sender must go quiet before the packets that
+// follow are taken to be a separate flush.
+const defaultQuietGap = 10 * time.Millisecond
+
+// statsdMetric is one line of a statsd datagram, "name:value|type|...".
+type statsdMetric struct {
+	Name  string
+	Value string
+	Type  string
+}
+
+// parseStatsd parses every well formed metric line in a statsd datagram and
+// skips the rest.
+func parseStatsd(payload []byte) []statsdMetric {
+	metrics := []statsdMetric{}
+	for _, line := range strings.Split(string(payload), "\n") {
+		colon := strings.Index(line, ":")
+		if colon <= 0 {
+			continue
+		}
+		fields := strings.Split(line[colon+1:], "|")
+		if len(fields) < 2 {
+			continue
+		}
+		metrics = append(metrics, statsdMetric{Name: line[:colon], Value: fields[0], Type: fields[1]})
+	}
+	return metrics
+}
+
+// WithCountersOnly restricts ShouldFinalFlushCoverEarlierMetrics to counters.
+func WithCountersOnly() Option {
+	return func(c *callConfig) {
+		c.counters = true
+	}
+}
+
+// WithQuietGap sets how long the sender must go quiet between two flushes.
+func WithQuietGap(d time.Duration) Option {
+	return func(c *callConfig) {
+		c.quietGap = d
+	}
+}
+
+// ShouldFinalFlushCoverEarlierMetrics will fire a test error unless the last
+// flush of statsd metrics the given function sends names every metric sent in
+// the flushes before it. Flushes are told apart by the sender going quiet for
+// at least the quiet gap (10ms unless set with WithQuietGap).
+func (s *Server) ShouldFinalFlushCoverEarlierMetrics(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	if cfg.quietGap == 0 {
+		cfg.quietGap = defaultQuietGap
+	}
+	packets := c.capturePackets(t, body, cfg)
+
+	split := 0
+	for i := 1; i < len(packets); i++ {
+		if packets[i].Time.Sub(packets[i-1].Time) >= cfg.quietGap {
+			split = i
+		}
+	}
+	if split == 0 {
+		c.printLocation(t)
+		c.errorF("Expected several flushes separated by at least %v, but got %d packets with no such gap", cfg.quietGap, len(packets))
+		return
+	}
+
+	names := func(packets []Packet) map[string]bool {
+		set := map[string]bool{}
+		for _, p := range packets {
+			for _, m := range parseStatsd(p.Payload) {
+				if !cfg.counters || m.Type == "c" {
+					set[m.Name] = true
+				}
+			}
+		}
+		return set
+	}
+	final := names(packets[split:])
+	missing := []string{}
+	for name := range names(packets[:split]) {
+		if !final[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		c.printLocation(t)
+		c.errorF("Expected the final flush to include every earlier metric, but it is missing: %#v", missing)
+	}
+}
+
+// ShouldFinalFlushCoverEarlierMetrics calls
+// Server.ShouldFinalFlushCoverEarlierMetrics on the default server.
+func ShouldFinalFlushCoverEarlierMetrics(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldFinalFlushCoverEarlierMetrics(t, body, opts...)
+}
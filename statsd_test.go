@@ -0,0 +1,59 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatsd(t *testing.T) {
+	metrics := parseStatsd([]byte("foo:1|c\nbar:2.5|g|@0.1\nnonsense\nbaz:3|ms|#tag:a"))
+	expected := []statsdMetric{
+		{"foo", "1", "c"},
+		{"bar", "2.5", "g"},
+		{"baz", "3", "ms"},
+	}
+	if len(metrics) != len(expected) {
+		t.Fatalf("Expected %#v but got %#v", expected, metrics)
+	}
+	for i := range expected {
+		if metrics[i] != expected[i] {
+			t.Errorf("Expected %#v but got %#v", expected[i], metrics[i])
+		}
+	}
+}
+
+func TestShouldFinalFlushCoverEarlierMetrics(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldFinalFlushCoverEarlierMetrics(t, func() {
+		udpClient.Write([]byte("hits:1|c\nload:2|g"))
+		time.Sleep(30 * time.Millisecond)
+		udpClient.Write([]byte("hits:3|c"))
+		udpClient.Write([]byte("load:1|g"))
+	})
+
+	ShouldFinalFlushCoverEarlierMetrics(t, func() {
+		udpClient.Write([]byte("hits:1|c\nload:2|g"))
+		time.Sleep(30 * time.Millisecond)
+		udpClient.Write([]byte("hits:3|c"))
+	}, WithCountersOnly())
+
+	ft := &fakeT{}
+	ShouldFinalFlushCoverEarlierMetrics(ft, func() {
+		udpClient.Write([]byte("hits:1|c\nload:2|g"))
+		time.Sleep(30 * time.Millisecond)
+		udpClient.Write([]byte("hits:3|c"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "load") || strings.Contains(ft.errors[0], "hits") {
+		t.Errorf("Expected the final flush to be reported missing load, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldFinalFlushCoverEarlierMetrics(ft, func() {
+		udpClient.Write([]byte("hits:1|c"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "no such gap") {
+		t.Errorf("Expected a missing gap error, got %#v", ft.errors)
+	}
+}
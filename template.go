@@ -0,0 +1,131 @@
+package udp
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// templatePlaceholders are the expressions the placeholders of
+// ShouldReceiveTemplate stand for.
+var templatePlaceholders = map[byte]string{
+	'd': `[-+]?\d+`,
+	'f': `[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`,
+	's': `\S+`,
+	'*': `[^|]*`,
+}
+
+// template is a parsed ShouldReceiveTemplate template: literals[i] is the
+// text before placeholders[i], and the last literal the text after them all.
+type template struct {
+	literals     []string
+	placeholders []string
+}
+
+func parseTemplate(tmpl string) (*template, error) {
+	parsed := &template{}
+	literal := ""
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' {
+			literal += tmpl[i : i+1]
+			continue
+		}
+		i++
+		if i == len(tmpl) {
+			return nil, fmt.Errorf("template %q ends with a lone %%", tmpl)
+		}
+		if tmpl[i] == '%' {
+			literal += "%"
+			continue
+		}
+		if _, ok := templatePlaceholders[tmpl[i]]; !ok {
+			return nil, fmt.Errorf("template %q has an unknown placeholder %%%c", tmpl, tmpl[i])
+		}
+		parsed.literals = append(parsed.literals, literal)
+		parsed.placeholders = append(parsed.placeholders, tmpl[i-1:i+1])
+		literal = ""
+	}
+	parsed.literals = append(parsed.literals, literal)
+	return parsed, nil
+}
+
+// prefix returns an expression for the template up to its first n
+// placeholders, capturing each of them, and the text after the last of them
+// if trailing is set.
+func (tmpl *template) prefix(n int, trailing bool) *regexp.Regexp {
+	expr := regexp.QuoteMeta(tmpl.literals[0])
+	for i := 0; i < n; i++ {
+		expr += "(" + templatePlaceholders[tmpl.placeholders[i][1]] + ")"
+		if i < n-1 || trailing {
+			expr += regexp.QuoteMeta(tmpl.literals[i+1])
+		}
+	}
+	return regexp.MustCompile(expr)
+}
+
+// match matches the template against got. It returns how many placeholders
+// matched, along with the text before them, and what each of those matched;
+// and whether the text after the last of them failed to match. The whole
+// template matched if the count is len(tmpl.placeholders) and the text after
+// them did not fail.
+func (tmpl *template) match(got string) (int, []string, bool) {
+	for n := len(tmpl.placeholders); n > 0; n-- {
+		if m := tmpl.prefix(n, true).FindStringSubmatch(got); m != nil {
+			return n, m[1:], false
+		}
+		if m := tmpl.prefix(n, false).FindStringSubmatch(got); m != nil {
+			return n, m[1:], true
+		}
+	}
+	if tmpl.prefix(0, true).MatchString(got) {
+		return 0, nil, false
+	}
+	return -1, nil, false
+}
+
+// ShouldReceiveTemplate will fire a test error unless what the given function
+// sends over UDP, concatenated, contains a match for the given template. In
+// the template, %d stands for an integer, %f for a decimal number, %s for a
+// run of non-space characters, %* for a run of anything but '|', and %% for a
+// percent sign. On failure the first placeholder, or the text after one, that
+// didn't match is named, along with what the placeholders before it matched.
+func (s *Server) ShouldReceiveTemplate(t TestingT, tmpl string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	parsed, err := parseTemplate(tmpl)
+	if err != nil {
+		t.Fatal(fmt.Sprintf("udp: invalid template at %s: %v", callerLocation(), err))
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, false, newCallConfig(opts))
+	n, values, trailingFailed := parsed.match(got)
+	if n == len(parsed.placeholders) && !trailingFailed {
+		return
+	}
+	c.printLocation(t)
+	c.errorF("Expected to match template: %#v", tmpl)
+	if n < 0 {
+		c.errorF("But no text matched %#v", parsed.literals[0])
+	} else {
+		for i, v := range values {
+			c.errorF("Placeholder %d (%s) matched %#v", i+1, parsed.placeholders[i], v)
+		}
+		if trailingFailed {
+			c.errorF("The text after placeholder %d (%s) failed to match %#v", n, parsed.placeholders[n-1], parsed.literals[n])
+		} else {
+			c.errorF("Placeholder %d (%s) failed to match", n+1, parsed.placeholders[n])
+		}
+	}
+	c.errorF("But got: %#v", got)
+}
+
+// ShouldReceiveTemplate calls Server.ShouldReceiveTemplate on the default
+// server.
+func ShouldReceiveTemplate(t TestingT, tmpl string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveTemplate(t, tmpl, body, opts...)
+}
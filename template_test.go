@@ -0,0 +1,36 @@
+package udp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShouldReceiveTemplate(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("api.latency:12.5|ms|#host:web-1"))
+	}
+
+	ShouldReceiveTemplate(t, "api.latency:%f|ms|#host:%*", send)
+	ShouldReceiveTemplate(t, "%s.latency:%d", send)
+
+	ft := &fakeT{}
+	ShouldReceiveTemplate(ft, "api.latency:%f|%d|#host:%s", send)
+	ShouldReceiveTemplate(ft, "db.latency:%f", send)
+	ShouldReceiveTemplate(ft, "100%", send)
+	ShouldReceiveTemplate(ft, "api.latency:%f|s", send)
+	want := "Expected to match template: \"api.latency:%f|%d|#host:%s\"\n" +
+		"Placeholder 1 (%f) matched \"12.5\"\n" +
+		"Placeholder 2 (%d) failed to match\n" +
+		"But got: \"api.latency:12.5|ms|#host:web-1\""
+	trailing := "Placeholder 1 (%f) matched \"12.5\"\n" +
+		"The text after placeholder 1 (%f) failed to match \"|s\"\n"
+	if len(ft.errors) != 3 || !strings.HasSuffix(ft.errors[0], want) ||
+		!strings.Contains(ft.errors[1], "But no text matched \"db.latency:\"") ||
+		!strings.Contains(ft.errors[2], trailing) {
+		t.Errorf("Expected the failing placeholders to be named, got %#v", ft.errors)
+	}
+	if len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "ends with a lone %") {
+		t.Errorf("Expected an invalid template to be fatal, got %#v", ft.fatals)
+	}
+}
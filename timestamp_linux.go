@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// enableTimestamps asks the kernel to attach its receive timestamp to each
+// packet as a control message.
+func enableTimestamps(conn *net.UDPConn) error {
+	return setSockOpt(conn, syscall.SOL_SOCKET, syscall.SO_TIMESTAMPNS, 1)
+}
+
+// parseTimestamp extracts the receive timestamp from the control messages read
+// alongside a packet.
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, m := range msgs {
+		if m.Header.Level == syscall.SOL_SOCKET && m.Header.Type == syscall.SCM_TIMESTAMPNS &&
+			len(m.Data) >= int(unsafe.Sizeof(syscall.Timespec{})) {
+			ts := (*syscall.Timespec)(unsafe.Pointer(&m.Data[0]))
+			return time.Unix(ts.Unix()), true
+		}
+	}
+	return time.Time{}, false
+}
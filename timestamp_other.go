@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package udp
+
+import (
+	"net"
+	"time"
+)
+
+// enableTimestamps is a no-op where kernel timestamps aren't read; packets are
+// timestamped when they are read instead.
+func enableTimestamps(conn *net.UDPConn) error {
+	return nil
+}
+
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// enableTTL asks the kernel to attach the IP TTL (or IPv6 hop limit) of each
+// received packet as a control message.
+func enableTTL(conn *net.UDPConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTTL, 1)
+		if sockErr != nil {
+			return
+		}
+		// Dual-stack sockets also need the IPv6 option; a pure IPv4 socket
+		// rejects it, which is harmless.
+		syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVHOPLIMIT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// parseTTL extracts the TTL from the control messages read alongside a packet.
+func parseTTL(oob []byte) (int, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+	for _, m := range msgs {
+		isTTL := m.Header.Level == syscall.IPPROTO_IP && m.Header.Type == syscall.IP_TTL
+		isHopLimit := m.Header.Level == syscall.IPPROTO_IPV6 && m.Header.Type == syscall.IPV6_HOPLIMIT
+		if (isTTL || isHopLimit) && len(m.Data) >= 4 {
+			return int(*(*int32)(unsafe.Pointer(&m.Data[0]))), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestShouldReceiveWithTTL(t *testing.T) {
+	udpClient := setup(t)
+
+	raw, err := udpClient.(*net.UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Control(func(fd uintptr) {
+		// Only one of these applies depending on the socket family.
+		syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, 7)
+		syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, 7)
+	})
+
+	ShouldReceiveWithTTL(t, 7, func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveWithTTL(ft, 64, func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected a TTL mismatch error, got %#v", ft.errors)
+	}
+
+	ledger := NewLedger()
+	ShouldReceiveWithTTL(t, 7, func() {
+		udpClient.Write([]byte("ttl-ledger"))
+	}, WithLedger(ledger))
+	ledger.AssertExactlyOnce(t, "ttl-ledger")
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package udp
+
+import (
+	"errors"
+	"net"
+)
+
+func enableTTL(conn *net.UDPConn) error {
+	return errors.New("udp: reading packet TTLs is only supported on linux")
+}
+
+func parseTTL(oob []byte) (int, bool) {
+	return 0, false
+}
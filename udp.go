@@ -3,20 +3,36 @@
 package udp
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
-)
 
-var (
-	addr     *string
-	listener *net.UDPConn
-	Timeout  time.Duration = time.Millisecond
-	logBuf   []string
+	"github.com/urjitbhatia/go-udp-testing/quic"
 )
 
+// Timeout is how long an assertion waits for another packet, unless a Server
+// sets its own.
+var Timeout time.Duration = time.Millisecond
+
+// maxDatagramSize is the largest UDP payload, so a read buffer this size never
+// truncates a packet.
+const maxDatagramSize = 65535
+
+// logLine is a line of a failure message. It is only formatted when emitted,
+// so rendering a large capture never happens while packets are being read.
+type logLine struct {
+	format string
+	args   []interface{}
+}
+
+type sockOpt struct {
+	level, optname, optval int
+}
+
 // TestingT is an interface wrapper around TestingT
 // Makes this tester play nice with Ginkgo
 type TestingT interface {
@@ -25,211 +41,1567 @@ type TestingT interface {
 	Fatal(args ...interface{})
 }
 
-func resetLogBuf() {
-	logBuf = []string{}
+// HelperT is implemented by test runners, like *testing.T, that can leave the
+// functions marked as helpers out of the locations they report. Assertions
+// mark themselves when t implements it, so failures point at the test.
+type HelperT interface {
+	Helper()
 }
 
-func errorF(format string, args ...interface{}) {
-	logBuf = append(logBuf, fmt.Sprintf(format, args))
+type fn func()
+
+// Option configures a single assertion call.
+type Option func(*callConfig)
+
+type callConfig struct {
+	extraFields bool
+	seed        *int64
+	shrink      func(payload interface{}) []interface{}
+	shrinkSend  func(payload interface{}, addr string)
+	counters    bool
+	quietGap    time.Duration
+
+	strictWindowing bool
+	linger          time.Duration
+	maxWait         time.Duration
+	// until, if set, is when the capture ends, whatever is received.
+	until time.Time
+	// packetLimit, if set, ends the capture once that many packets are read,
+	// however long that takes.
+	packetLimit int
+
+	entropyThreshold     float64
+	compressionThreshold float64
+	minPacketSize        int
+	minSamples           int
+
+	merge       MergePolicy
+	normalizers []func(payload []byte) []byte
+	strictOrder bool
+
+	genesis     bool
+	chainFilter func(payload []byte) bool
+
+	partitionBy func(payload []byte) string
+
+	requiredProbes []string
+
+	warnOnNoData     bool
+	perPacket        bool
+	events           eventSink
+	timeout          time.Duration
+	ctx              context.Context
+	ignoreWhitespace bool
+
+	ledger *Ledger
+
+	exclusiveUse bool
+	allowedPorts []int
+
+	fatal bool
+	// ttl makes the capture ask for the IP TTL of each packet.
+	ttl bool
+
+	// received, if set, is called with the packets each capture read, for
+	// the Expect functions to return what was got.
+	received func(packets []Packet)
 }
 
-func emitLog(t TestingT) {
-	if len(logBuf) > 0 {
-		t.Error(strings.Join(logBuf, "\n"))
-		resetLogBuf()
+func newCallConfig(opts []Option) *callConfig {
+	c := &callConfig{}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
-type fn func()
+// WithLinger keeps the capture reading for at least d after the body returns,
+// however long the gaps between packets, to catch packets still in flight. The
+// capture ends at whichever is later: d after the body returns, or Timeout
+// after the last packet.
+func WithLinger(d time.Duration) Option {
+	return func(c *callConfig) {
+		c.linger = d
+	}
+}
+
+// WithWarnOnNoData logs a warning when a "not receive" assertion passes
+// because nothing at all was received, which usually means the body never
+// sent what it was meant to.
+func WithWarnOnNoData() Option {
+	return func(c *callConfig) {
+		c.warnOnNoData = true
+	}
+}
 
-// SetAddr sets the UDP port that will be listened on.
+// lingerEnd returns when lingering ends for a body that has just returned.
+func (c *callConfig) lingerEnd() time.Time {
+	if !c.until.IsZero() {
+		return c.until
+	}
+	if c.linger == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.linger)
+}
+
+// WithTimeout makes the assertion wait d for another packet, rather than the
+// server's Timeout, so that one slow sender doesn't slow down every test.
+func WithTimeout(d time.Duration) Option {
+	return func(c *callConfig) {
+		c.timeout = d
+	}
+}
+
+// waitEnd returns when the capture stops for a body that has just returned,
+// however busy the sender still is, if MeasureDrain set a limit.
+func (c *callConfig) waitEnd() time.Time {
+	if !c.until.IsZero() {
+		return c.until
+	}
+	if c.maxWait == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.maxWait)
+}
+
+// idleTimeout returns how long the capture on s waits for another packet.
+func (c *callConfig) idleTimeout(s *Server) time.Duration {
+	if c.timeout == 0 {
+		return s.timeout()
+	}
+	return c.timeout
+}
+
+// WithExtraFields lets ShouldReceiveFieldsInOrder accept lines that have more
+// fields than there are patterns.
+func WithExtraFields() Option {
+	return func(c *callConfig) {
+		c.extraFields = true
+	}
+}
+
+// SetAddr sets the UDP port the package level assertions listen on. It panics
+// if a is not a valid UDP address, so a typo fails the test setup rather than
+// its first assertion.
+//
+// With port 0, as in ":0", SetAddr binds an ephemeral port right away and
+// keeps it bound until the next SetAddr, like NewServer does, so that Addr can
+// tell where to send packets. Packets sent between two assertions are then
+// read by the second.
 func SetAddr(a string) {
-	addr = &a
+	resAddr, err := net.ResolveUDPAddr("udp", a)
+	if err != nil {
+		panic(fmt.Sprintf("udp: SetAddr(%q): %v", a, err))
+	}
+	var conn *net.UDPConn
+	if resAddr.Port == 0 {
+		if conn, err = net.ListenUDP("udp", resAddr); err != nil {
+			panic(fmt.Sprintf("udp: SetAddr(%q): %v", a, err))
+		}
+	}
+	defaultServer.Close()
+	defaultServer.addr = &a
+	defaultServer.conn = conn
+}
+
+// ListenOnFreePort binds a port the system picks for the package level
+// assertions, as SetAddr(":0") does, and returns it.
+func ListenOnFreePort() (port int, err error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return 0, err
+	}
+	a := conn.LocalAddr().String()
+	defaultServer.Close()
+	defaultServer.addr = &a
+	defaultServer.conn = conn
+	return conn.LocalAddr().(*net.UDPAddr).Port, nil
+}
+
+// Addr returns the address the package level assertions listen on: the port
+// bound for SetAddr(":0"), or the address given to SetAddr.
+func Addr() string {
+	return defaultServer.Addr()
+}
+
+// SetSockOpt sets an integer socket option on the server's socket. If the
+// server keeps a socket bound, as NewServer's does, the option is set on it
+// straight away and any error returned; otherwise it is applied at the start of
+// every following assertion. See the package level SetSockOpt.
+func (s *Server) SetSockOpt(level, optname, optval int) error {
+	if s.conn != nil {
+		if err := setSockOpt(s.conn, level, optname, optval); err != nil {
+			return err
+		}
+	}
+	s.sockOpts = append(s.sockOpts, sockOpt{level, optname, optval})
+	return nil
+}
+
+// SetSockOpt sets an integer socket option on the UDP listener used by every
+// following assertion, for options this package has no dedicated API for
+// (SO_RCVBUF, SO_PRIORITY, IP_MULTICAST_TTL, etc.). Options are applied right
+// after the listener is bound, in the order they were set, or straight away if
+// SetAddr(":0") already bound it, in which case an error is returned. An option
+// that fails when an assertion binds the listener fails that assertion.
+//
+// It is a thin wrapper around syscall.SetsockoptInt, run on the listener's file
+// descriptor through the same pattern you would use on any *net.UDPConn:
+//
+//	raw, _ := conn.SyscallConn()
+//	raw.Control(func(fd uintptr) {
+//		syscall.SetsockoptInt(int(fd), level, optname, optval)
+//	})
+func SetSockOpt(level, optname, optval int) error {
+	return defaultServer.SetSockOpt(level, optname, optval)
 }
 
-func start(t TestingT) {
-	resAddr, err := net.ResolveUDPAddr("udp", *addr)
+// start binds the listener for the assertion. It returns false, having failed
+// the test, if it can't; the caller must then return without calling stop, as
+// TestingT implementations other than testing.T carry on after Fatal.
+func (c *call) start(t TestingT, cfg *callConfig) bool {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c.fatal = cfg.fatal
+	if c.s.conn != nil {
+		c.openWindow(t, cfg)
+		c.listener = c.s.conn
+		if err := c.applySockOpts(); err != nil {
+			c.s.closeWindow()
+			t.Fatal(err)
+			return false
+		}
+		return true
+	}
+	if c.s.addr == nil || *c.s.addr == "" {
+		t.Fatal("udp: SetAddr must be called before any assertion")
+		return false
+	}
+	resAddr, err := net.ResolveUDPAddr("udp", *c.s.addr)
 	if err != nil {
 		t.Fatal(err)
+		return false
 	}
-	listener, err = net.ListenUDP("udp", resAddr)
+	c.listener, err = net.ListenUDP("udp", resAddr)
 	if err != nil {
 		t.Fatal(err)
+		return false
 	}
+	c.openWindow(t, cfg)
+	if err := c.applySockOpts(); err != nil {
+		c.s.closeWindow()
+		c.listener.Close()
+		t.Fatal(err)
+		return false
+	}
+	return true
 }
 
-func stop(t TestingT) {
-	if err := listener.Close(); err != nil {
-		t.Fatal(err)
+// applySockOpts sets the server's socket options on the listener.
+func (c *call) applySockOpts() error {
+	for _, opt := range c.s.sockOpts {
+		if err := setSockOpt(c.listener, opt.level, opt.optname, opt.optval); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func getMessage(t TestingT, body fn, expectData bool) string {
-	start(t)
-	defer stop(t)
-	body()
+// stop closes the listener, unless it is a socket the server keeps bound. It
+// must be deferred: if the body panicked, the listener is still closed and the
+// panic carries on. A failure to close is reported with t.Error, since t.Fatal
+// would skip the caller's other cleanup.
+func (c *call) stop(t TestingT) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c.s.closeWindow()
+	var err error
+	if c.s.conn == nil {
+		err = c.listener.Close()
+	}
+	if r := recover(); r != nil {
+		panic(r)
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
 
-	message := make([]byte, 1024*32)
-	var bufLen int
-	for {
-		listener.SetReadDeadline(time.Now().Add(Timeout))
-		n, _, err := listener.ReadFrom(message[bufLen:])
-		if n == 0 {
-			if err != nil && bufLen == 0 && expectData {
-				errorF("Error reading udp data: %v", err)
-			}
-			break
-		} else {
-			bufLen += n
-		}
+func (c *call) captureMessage(t TestingT, body fn, expectData bool, cfg *callConfig) string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !applyBudget(t, c.s, cfg) {
+		return ""
+	}
+	defer chargeBudget(t, time.Now())
+	if !c.start(t, cfg) {
+		return ""
+	}
+	defer c.stop(t)
+	defer c.reportForeign(t)
+	return c.readMessage(c.listener, body, expectData, cfg)
+}
+
+// captureBytes is captureMessage for binary payloads, sparing the caller a
+// conversion to string and back.
+func (c *call) captureBytes(t TestingT, body fn, expectData bool, cfg *callConfig) []byte {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !applyBudget(t, c.s, cfg) {
+		return nil
+	}
+	defer chargeBudget(t, time.Now())
+	if !c.start(t, cfg) {
+		return nil
+	}
+	defer c.stop(t)
+	defer c.reportForeign(t)
+	return c.readBytes(c.listener, body, expectData, cfg)
+}
+
+// readDeadline returns when to give up waiting for the next packet: idle from
+// now, but never before lingerEnd, nor after waitEnd if it is set.
+func readDeadline(idle time.Duration, lingerEnd, waitEnd time.Time) time.Time {
+	deadline := time.Now().Add(idle)
+	if deadline.Before(lingerEnd) {
+		deadline = lingerEnd
+	}
+	if !waitEnd.IsZero() && deadline.After(waitEnd) {
+		return waitEnd
+	}
+	return deadline
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// readMessage returns everything sent to conn while body runs, and until no
+// packet has arrived for the idle timeout after it returns, concatenated.
+func (c *call) readMessage(conn *net.UDPConn, body fn, expectData bool, cfg *callConfig) string {
+	return string(c.readBytes(conn, body, expectData, cfg))
+}
+
+// readBytes is readMessage returning the bytes received.
+func (c *call) readBytes(conn *net.UDPConn, body fn, expectData bool, cfg *callConfig) []byte {
+	packets, err := c.readPacketsFrom([]*net.UDPConn{conn}, body, cfg)
+	if len(packets) == 0 && expectData && isTimeout(err) {
+		c.errorF("Error reading udp data: %v", err)
+	}
+	var msg []byte
+	for _, p := range packets {
+		msg = append(msg, p.Payload...)
 	}
-	msg := string(message[0:bufLen])
 	return msg
 }
 
-func get(t TestingT, match string, body fn, expectData bool) (got string, equals bool, contains bool) {
-	got = getMessage(t, body, expectData)
+// getTTLs returns the IP TTL of every packet the given function sends.
+// Packets whose TTL cannot be determined are reported as -1.
+func (c *call) getTTLs(t TestingT, body fn, cfg *callConfig) []int {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	cfg.ttl = true
+	packets := c.capturePackets(t, body, cfg)
+	if packets == nil {
+		return nil
+	}
+	ttls := make([]int, len(packets))
+	for i, p := range packets {
+		ttls[i] = p.ttl
+	}
+	return ttls
+}
+
+func (c *call) get(t TestingT, match string, body fn, expectData bool, cfg *callConfig) (got string, equals bool, contains bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	got = c.captureMessage(t, body, expectData, cfg)
 	equals = got == match
 	contains = strings.Contains(got, match)
 	return got, equals, contains
 }
 
-func printLocation(t TestingT) {
-	_, file, line, _ := runtime.Caller(2)
-	errorF("At: %s:%d", file, line)
+// warnOnNoData logs a warning, if WithWarnOnNoData was given, that a "not
+// receive" assertion passed only because nothing at all was received.
+func warnOnNoData(t TestingT, got string, cfg *callConfig) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	if !cfg.warnOnNoData || len(got) > 0 {
+		return
+	}
+	if l, ok := t.(logger); ok {
+		l.Logf("udp: warning: no data was received at %s", callerLocation())
+	}
+}
+
+// callerLocation returns the file and line of the first caller outside this
+// package, which is the assertion call being reported on, however deep within
+// the package it is asked for.
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if strings.HasSuffix(frame.File, "_test.go") || !strings.HasPrefix(frame.Function, "github.com/urjitbhatia/go-udp-testing.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// assertionName returns the name of the outermost function of this package on
+// the stack, which is the assertion being made.
+func assertionName() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	name := ""
+	for {
+		frame, more := frames.Next()
+		if strings.HasSuffix(frame.File, "_test.go") || !strings.HasPrefix(frame.Function, "github.com/urjitbhatia/go-udp-testing.") {
+			return name
+		}
+		name = frame.Function[strings.LastIndex(frame.Function, ".")+1:]
+		if !more {
+			return name
+		}
+	}
+}
+
+// printLocation starts the failure message with the location of the
+// assertion, for test runners that don't implement HelperT. Those that do
+// report it themselves, and correctly even when the assertion is wrapped in
+// the caller's own helpers.
+func (c *call) printLocation(t TestingT) {
+	if _, ok := t.(HelperT); ok || c.located {
+		return
+	}
+	c.located = true
+	c.errorF("At: %s", callerLocation())
 }
 
 // ShouldReceiveOnly will fire a test error if the given function doesn't send
 // exactly the given string over UDP.
-func ShouldReceiveOnly(t TestingT, expected string, body fn) {
-	defer emitLog(t)
-	got, equals, _ := get(t, expected, body, true)
+func (s *Server) ShouldReceiveOnly(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got, equals, _ := c.get(t, expected, body, true, newCallConfig(opts))
 	if !equals {
-		printLocation(t)
-		errorF("Expected: %#v", expected)
-		errorF("But got: %#v", got)
+		c.printLocation(t)
+		c.errorF("Expected: %#v", expected)
+		c.errorF("But got: %#v", got)
 	}
 }
 
+// ShouldReceiveOnly calls Server.ShouldReceiveOnly on the default server.
+func ShouldReceiveOnly(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveOnly(t, expected, body, opts...)
+}
+
+// ShouldReceiveComparedWith will fire a test error unless cmp reports what the
+// given function sends over UDP to be equal to expected. It is
+// ShouldReceiveOnly with the caller's own notion of equality.
+func (s *Server) ShouldReceiveComparedWith(t TestingT, expected string, cmp func(got, expected string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, true, newCallConfig(opts))
+	if !cmp(got, expected) {
+		c.printLocation(t)
+		c.errorF("Expected: %#v", expected)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldReceiveComparedWith calls Server.ShouldReceiveComparedWith on the
+// default server.
+func ShouldReceiveComparedWith(t TestingT, expected string, cmp func(got, expected string) bool, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveComparedWith(t, expected, cmp, body, opts...)
+}
+
 // ShouldNotReceiveOnly will fire a test error if the given function sends
-// exactly the given string over UDP.
-func ShouldNotReceiveOnly(t TestingT, notExpected string, body fn) {
-	defer emitLog(t)
-	_, equals, _ := get(t, notExpected, body, false)
-	if equals {
-		printLocation(t)
-		errorF("Expected not to get: %#v", notExpected)
+// exactly the given string over UDP. Note that it passes when nothing at all is
+// sent; use ShouldReceiveSomethingButNot if an empty capture should fail too.
+func (s *Server) ShouldNotReceiveOnly(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.captureMessage(t, body, false, cfg)
+	warnOnNoData(t, got, cfg)
+	if got == notExpected {
+		c.printLocation(t)
+		c.errorF("Expected not to get: %#v", notExpected)
+	}
+}
+
+// ShouldNotReceiveOnly calls Server.ShouldNotReceiveOnly on the default server.
+func ShouldNotReceiveOnly(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceiveOnly(t, notExpected, body, opts...)
+}
+
+// ShouldReceiveSomethingButNot will fire a test error if the given function
+// sends nothing over UDP, or if it sends exactly the given string.
+func (s *Server) ShouldReceiveSomethingButNot(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got, equals, _ := c.get(t, notExpected, body, false, newCallConfig(opts))
+	if len(got) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected some data other than: %#v", notExpected)
+		c.errorF("But got no data (ShouldNotReceiveOnly would have passed)")
+	} else if equals {
+		c.printLocation(t)
+		c.errorF("Expected not to get: %#v", notExpected)
+	}
+}
+
+// ShouldReceiveSomethingButNot calls Server.ShouldReceiveSomethingButNot on the
+// default server.
+func ShouldReceiveSomethingButNot(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveSomethingButNot(t, notExpected, body, opts...)
 }
 
 // ShouldReceive will fire a test error if the given function doesn't send the
 // given string over UDP.
-func ShouldReceive(t TestingT, expected string, body fn) {
-	defer emitLog(t)
-	got, _, contains := get(t, expected, body, false)
-	if !contains {
-		printLocation(t)
-		errorF("Expected: %#v", expected)
-		errorF("But got: %#v", got)
+func (s *Server) ShouldReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, false, newCallConfig(opts))
+	if !strings.Contains(got, expected) {
+		c.printLocation(t)
+		c.errorF("Expected: %#v", expected)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldReceive calls Server.ShouldReceive on the default server.
+func ShouldReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	defaultServer.ShouldReceive(t, expected, body, opts...)
+}
+
+// ShouldReceiveTransformed will fire a test error unless what the given
+// function sends over UDP contains the given string once passed through
+// transform, for example to sort lines or strip timestamps.
+func (s *Server) ShouldReceiveTransformed(t TestingT, transform func(string) string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, false, newCallConfig(opts))
+	transformed := transform(got)
+	if !strings.Contains(transformed, expected) {
+		c.printLocation(t)
+		c.errorF("Expected: %#v", expected)
+		c.errorF("But got: %#v", got)
+		c.errorF("Transformed to: %#v", transformed)
+	}
+}
+
+// ShouldReceiveTransformed calls Server.ShouldReceiveTransformed on the
+// default server.
+func ShouldReceiveTransformed(t TestingT, transform func(string) string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveTransformed(t, transform, expected, body, opts...)
 }
 
 // ShouldNotReceive will fire a test error if the given function sends the
 // given string over UDP.
-func ShouldNotReceive(t TestingT, expected string, body fn) {
-	defer emitLog(t)
-	got, _, contains := get(t, expected, body, false)
-	if contains {
-		printLocation(t)
-		errorF("Expected not to find: %#v", expected)
-		errorF("But got: %#v", got)
+func (s *Server) ShouldNotReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.captureMessage(t, body, false, cfg)
+	warnOnNoData(t, got, cfg)
+	if strings.Contains(got, expected) {
+		c.printLocation(t)
+		c.errorF("Expected not to find: %#v", expected)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldNotReceive calls Server.ShouldNotReceive on the default server.
+func ShouldNotReceive(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	defaultServer.ShouldNotReceive(t, expected, body, opts...)
 }
 
 // ShouldReceiveNothing will fire a test error if the given function sends any
 // data over UDP.
-func ShouldReceiveNothing(t TestingT, body fn) {
-	defer emitLog(t)
-	got, _, _ := get(t, "", body, false)
+func (s *Server) ShouldReceiveNothing(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got, _, _ := c.get(t, "", body, false, newCallConfig(opts))
 	if len(got) > 0 {
-		printLocation(t)
-		errorF("Expected no data, but got: %#v", got)
+		c.printLocation(t)
+		c.errorF("Expected no data, but got: %#v", got)
+	}
+}
+
+// ShouldReceiveNothing calls Server.ShouldReceiveNothing on the default server.
+func ShouldReceiveNothing(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	defaultServer.ShouldReceiveNothing(t, body, opts...)
 }
 
 // ShouldReceiveAll will fire a test error unless all of the given strings are
 // sent over UDP.
-func ShouldReceiveAll(t TestingT, expected []string, body fn) {
-	defer emitLog(t)
-	got := getMessage(t, body, true)
+func (s *Server) ShouldReceiveAll(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, true, newCallConfig(opts))
 	failed := false
 
 	for _, str := range expected {
 		if !strings.Contains(got, str) {
 			if !failed {
-				printLocation(t)
+				c.printLocation(t)
+				if len(got) == 0 {
+					c.errorF("No data was received")
+				}
 				failed = true
 			}
-			errorF("Expected to find: %#v", str)
+			c.errorF("Expected to find: %#v", str)
 		}
 	}
 
 	if failed {
-		errorF("But got: %#v", got)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldReceiveAll calls Server.ShouldReceiveAll on the default server.
+func ShouldReceiveAll(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAll(t, expected, body, opts...)
+}
+
+// ShouldReceiveInOrder will fire a test error unless the given strings are all
+// sent over UDP, in the given order. Each string is looked for after the start
+// of the one before it, in the same datagram or a later one, so two strings
+// may overlap; a string split across two datagrams is not found. Every string
+// out of place is reported, along with the datagrams received.
+func (s *Server) ShouldReceiveInOrder(t TestingT, ordered []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	failed := false
+
+	// The next string is looked for from packets[k][pos:] on.
+	k, pos := 0, 0
+	for i, str := range ordered {
+		found := false
+		for from, at := k, pos; from < len(packets); from, at = from+1, 0 {
+			if j := strings.Index(packets[from][at:], str); j >= 0 {
+				k, pos = from, at+j+1
+				if pos > len(packets[from]) {
+					pos = len(packets[from])
+				}
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if !failed {
+			c.printLocation(t)
+			failed = true
+		}
+		if i > 0 && anyContains(packets, str) {
+			c.errorF("Expected %#v after %#v", str, ordered[i-1])
+		} else {
+			c.errorF("Expected to find: %#v", str)
+		}
+	}
+
+	if failed {
+		c.errorF("But got packets:")
+		for i, p := range packets {
+			c.errorF("%d: %#v", i, p)
+		}
+	}
+}
+
+// ShouldReceiveInOrder calls Server.ShouldReceiveInOrder on the default server.
+func ShouldReceiveInOrder(t TestingT, ordered []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	defaultServer.ShouldReceiveInOrder(t, ordered, body, opts...)
+}
+
+// ShouldReceivePacketsInOrder will fire a test error unless each of the given
+// strings is found in a datagram the given function sends over UDP, each in a
+// later datagram than the string before it. Every string out of place is
+// reported.
+func (s *Server) ShouldReceivePacketsInOrder(t TestingT, ordered []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	failed := false
+
+	next := 0
+	for i, str := range ordered {
+		found := false
+		for k := next; k < len(packets); k++ {
+			if strings.Contains(packets[k], str) {
+				next, found = k+1, true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if !failed {
+			c.printLocation(t)
+			failed = true
+		}
+		if i > 0 && anyContains(packets, str) {
+			c.errorF("Expected %#v in a packet after %#v", str, ordered[i-1])
+		} else {
+			c.errorF("Expected a packet with: %#v", str)
+		}
+	}
+
+	if failed {
+		c.errorF("But got packets: %#v", packets)
+	}
+}
+
+// ShouldReceivePacketsInOrder calls Server.ShouldReceivePacketsInOrder on the
+// default server.
+func ShouldReceivePacketsInOrder(t TestingT, ordered []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceivePacketsInOrder(t, ordered, body, opts...)
+}
+
+func anyContains(packets []string, str string) bool {
+	for _, p := range packets {
+		if strings.Contains(p, str) {
+			return true
+		}
+	}
+	return false
 }
 
 // ShouldNotReceiveAny will fire a test error if any of the given strings are
 // sent over UDP.
-func ShouldNotReceiveAny(t TestingT, unexpected []string, body fn) {
-	defer emitLog(t)
-	got := getMessage(t, body, false)
+func (s *Server) ShouldNotReceiveAny(t TestingT, unexpected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	got := c.captureMessage(t, body, false, cfg)
+	warnOnNoData(t, got, cfg)
 	failed := false
 
 	for _, str := range unexpected {
 		if strings.Contains(got, str) {
 			if !failed {
-				printLocation(t)
+				c.printLocation(t)
 				failed = true
 			}
-			errorF("Expected not to find: %#v", str)
+			c.errorF("Expected not to find: %#v", str)
 		}
 	}
 
 	if failed {
-		errorF("But got: %#v", got)
+		c.errorF("But got: %#v", got)
+	}
+}
+
+// ShouldNotReceiveAny calls Server.ShouldNotReceiveAny on the default server.
+func ShouldNotReceiveAny(t TestingT, unexpected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
 	}
+	defaultServer.ShouldNotReceiveAny(t, unexpected, body, opts...)
 }
 
-func ShouldReceiveAllAndNotReceiveAny(t TestingT, expected []string, unexpected []string, body fn) {
-	defer emitLog(t)
-	got := getMessage(t, body, true)
+func (s *Server) ShouldReceiveAllAndNotReceiveAny(t TestingT, expected []string, unexpected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := c.captureMessage(t, body, true, newCallConfig(opts))
 	failed := false
 
 	for _, str := range expected {
 		if !strings.Contains(got, str) {
 			if !failed {
-				printLocation(t)
+				c.printLocation(t)
 				failed = true
 			}
-			errorF("Expected to find: %#v", str)
+			c.errorF("Expected to find: %#v", str)
 		}
 	}
 	for _, str := range unexpected {
 		if strings.Contains(got, str) {
 			if !failed {
-				printLocation(t)
+				c.printLocation(t)
 				failed = true
 			}
-			errorF("Expected not to find: %#v", str)
+			c.errorF("Expected not to find: %#v", str)
 		}
 	}
 
 	if failed {
-		errorF("but got: %#v", got)
+		c.errorF("but got: %#v", got)
+	}
+}
+
+// ShouldReceiveAllAndNotReceiveAny calls
+// Server.ShouldReceiveAllAndNotReceiveAny on the default server.
+func ShouldReceiveAllAndNotReceiveAny(t TestingT, expected []string, unexpected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAllAndNotReceiveAny(t, expected, unexpected, body, opts...)
+}
+
+// ShouldReceiveWithTTL will fire a test error if the given function sends no
+// data over UDP, or if any packet it sends arrives with an IP TTL other than
+// the given one. On loopback the TTL seen is always the one the sender set.
+func (s *Server) ShouldReceiveWithTTL(t TestingT, expectedTTL int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	ttls := c.getTTLs(t, body, newCallConfig(opts))
+	if len(ttls) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected packets with TTL %d, but got no data", expectedTTL)
+		return
+	}
+
+	failed := false
+	for i, ttl := range ttls {
+		if ttl != expectedTTL {
+			if !failed {
+				c.printLocation(t)
+				failed = true
+			}
+			c.errorF("Expected packet %d to have TTL %d, but got %d", i, expectedTTL, ttl)
+		}
+	}
+}
+
+// ShouldReceiveWithTTL calls Server.ShouldReceiveWithTTL on the default server.
+func ShouldReceiveWithTTL(t TestingT, expectedTTL int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveWithTTL(t, expectedTTL, body, opts...)
+}
+
+// ShouldReceiveFieldsInOrder will fire a test error if the given function sends
+// no data over UDP, or if any line it sends is not made of whitespace separated
+// fields matching the given regular expressions in order. Each pattern must
+// match its whole field. Lines with extra trailing fields fail unless
+// WithExtraFields is given.
+func (s *Server) ShouldReceiveFieldsInOrder(t TestingT, fieldPatterns []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	res := make([]*regexp.Regexp, len(fieldPatterns))
+	for i, pattern := range fieldPatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			t.Fatal(err)
+			return
+		}
+		res[i] = re
+	}
+
+	got := c.captureMessage(t, body, true, cfg)
+	if len(got) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected lines matching fields: %#v", fieldPatterns)
+		c.errorF("But got no data")
+		return
+	}
+
+	failed := false
+	for _, line := range strings.Split(got, "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		violation := ""
+		for i, re := range res {
+			if i >= len(fields) {
+				violation = fmt.Sprintf("Line %#v is missing field %d (%s)", line, i, fieldPatterns[i])
+				break
+			}
+			if !re.MatchString(fields[i]) {
+				violation = fmt.Sprintf("Line %#v field %d: expected %s, but got %#v", line, i, fieldPatterns[i], fields[i])
+				break
+			}
+		}
+		if violation == "" && len(fields) > len(res) && !cfg.extraFields {
+			violation = fmt.Sprintf("Line %#v has unexpected extra fields: %#v", line, fields[len(res):])
+		}
+		if violation != "" {
+			if !failed {
+				c.printLocation(t)
+				failed = true
+			}
+			c.errorF("%s", violation)
+		}
+	}
+}
+
+// ShouldReceiveFieldsInOrder calls Server.ShouldReceiveFieldsInOrder on the
+// default server.
+func ShouldReceiveFieldsInOrder(t TestingT, fieldPatterns []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveFieldsInOrder(t, fieldPatterns, body, opts...)
+}
+
+// ShouldReceiveQUICInitialWithSNI will fire a test error unless the given
+// function sends a QUIC version 1 client Initial packet whose ClientHello names
+// the given server. Version negotiation packets and non-QUIC traffic are
+// skipped, and counted in the failure message.
+func (s *Server) ShouldReceiveQUICInitialWithSNI(t TestingT, serverName string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	d := quic.NewDecoder()
+	for _, packet := range c.capturePackets(t, body, newCallConfig(opts)) {
+		d.Decode(packet.Payload)
+	}
+
+	names := d.ServerNames()
+	for _, name := range names {
+		if name == serverName {
+			return
+		}
+	}
+	c.printLocation(t)
+	c.errorF("Expected a QUIC Initial with SNI: %#v", serverName)
+	c.errorF("But got SNIs: %#v", names)
+	c.errorF("Decoded %d Initial packets, skipped %d version negotiation, %d other QUIC, %d non-QUIC and %d invalid packets",
+		d.Stats.Initial, d.Stats.VersionNegotiation, d.Stats.OtherQUIC, d.Stats.NonQUIC, d.Stats.Invalid)
+}
+
+// ShouldReceiveQUICInitialWithSNI calls Server.ShouldReceiveQUICInitialWithSNI
+// on the default server.
+func ShouldReceiveQUICInitialWithSNI(t TestingT, serverName string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveQUICInitialWithSNI(t, serverName, body, opts...)
+}
+
+// ShouldReceiveConnlessPackets will fire a test error if the given function
+// sends no data over UDP. Packets are accepted from any sender, as by every
+// other assertion, unless the server is a Listener from NewConnectedListener,
+// which only reads its peer's.
+func (s *Server) ShouldReceiveConnlessPackets(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	if len(c.capturePackets(t, body, newCallConfig(opts))) == 0 {
+		c.printLocation(t)
+		c.errorF("Expected packets from any sender, but got no data")
+	}
+}
+
+// ShouldReceiveConnlessPackets calls Server.ShouldReceiveConnlessPackets on the
+// default server.
+func ShouldReceiveConnlessPackets(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveConnlessPackets(t, body, opts...)
+}
+
+// ShouldReceiveEmptyPacket will fire a test error unless the given function
+// sends at least one zero-length datagram over UDP, as some protocols do for
+// keep-alives.
+func (s *Server) ShouldReceiveEmptyPacket(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	for _, p := range packets {
+		if len(p.Payload) == 0 {
+			return
+		}
+	}
+	c.printLocation(t)
+	c.errorF("Expected an empty packet, but got %d non-empty packets", len(packets))
+}
+
+// ShouldReceiveEmptyPacket calls Server.ShouldReceiveEmptyPacket on the default
+// server.
+func ShouldReceiveEmptyPacket(t TestingT, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveEmptyPacket(t, body, opts...)
+}
+
+// ShouldReceivePacket will fire a test error unless one of the datagrams the
+// given function sends over UDP is exactly the given string. Unlike
+// ShouldReceiveOnly, a message split across several datagrams fails.
+func (s *Server) ShouldReceivePacket(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	for _, p := range packets {
+		if string(p.Payload) == expected {
+			return
+		}
+	}
+	c.printLocation(t)
+	c.errorF("Expected a packet: %#v", expected)
+	c.errorF("But got packets: %#v", payloadStrings(packets))
+}
+
+// ShouldReceivePacket calls Server.ShouldReceivePacket on the default server.
+func ShouldReceivePacket(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceivePacket(t, expected, body, opts...)
+}
+
+// ShouldNotReceivePacket will fire a test error if any of the datagrams the
+// given function sends over UDP is exactly the given string.
+func (s *Server) ShouldNotReceivePacket(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	for _, p := range packets {
+		if string(p.Payload) == notExpected {
+			c.printLocation(t)
+			c.errorF("Expected no packet: %#v", notExpected)
+			c.errorF("But got packets: %#v", payloadStrings(packets))
+			return
+		}
+	}
+}
+
+// ShouldNotReceivePacket calls Server.ShouldNotReceivePacket on the default
+// server.
+func ShouldNotReceivePacket(t TestingT, notExpected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldNotReceivePacket(t, notExpected, body, opts...)
+}
+
+// ShouldReceiveExactly will fire a test error unless exactly count of the
+// datagrams the given function sends over UDP contain the given string. A
+// count of 0 asserts that none does.
+func (s *Server) ShouldReceiveExactly(t TestingT, count int, match string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	got := 0
+	for _, p := range packets {
+		if strings.Contains(p, match) {
+			got++
+		}
+	}
+	if got == count {
+		return
+	}
+	c.printLocation(t)
+	c.errorF("Expected %d packets containing: %#v", count, match)
+	c.errorF("But got %d, out of:", got)
+	for i, p := range packets {
+		if strings.Contains(p, match) {
+			c.errorF("%d: %#v (match)", i, p)
+		} else {
+			c.errorF("%d: %#v", i, p)
+		}
+	}
+}
+
+// ShouldReceiveExactly calls Server.ShouldReceiveExactly on the default server.
+func ShouldReceiveExactly(t TestingT, count int, match string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveExactly(t, count, match, body, opts...)
+}
+
+// ShouldReceiveOnce will fire a test error unless the given function sends
+// exactly one datagram over UDP, and it is exactly the given string. Unlike
+// ShouldReceiveOnly, a duplicated or split message fails.
+func (s *Server) ShouldReceiveOnce(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkOnce(t, body, opts, fmt.Sprintf("Expected the packet: %#v", expected), func(p string) bool { return p == expected })
+}
+
+// ShouldReceiveOnce calls Server.ShouldReceiveOnce on the default server.
+func ShouldReceiveOnce(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveOnce(t, expected, body, opts...)
+}
+
+// ShouldReceiveContainsOnce will fire a test error unless the given function
+// sends exactly one datagram over UDP, and it contains the given string.
+func (s *Server) ShouldReceiveContainsOnce(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkOnce(t, body, opts, fmt.Sprintf("Expected the packet to contain: %#v", expected), func(p string) bool { return strings.Contains(p, expected) })
+}
+
+// ShouldReceiveContainsOnce calls Server.ShouldReceiveContainsOnce on the
+// default server.
+func ShouldReceiveContainsOnce(t TestingT, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveContainsOnce(t, expected, body, opts...)
+}
+
+// checkOnce fires a test error unless the given function sends exactly one
+// datagram, and accept accepts it. want is the failure message if it doesn't.
+func (s *Server) checkOnce(t TestingT, body fn, opts []Option, want string, accept func(packet string) bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	if len(packets) != 1 {
+		c.printLocation(t)
+		c.errorF("Expected 1 packet, but got %d:", len(packets))
+		for i, p := range packets {
+			c.errorF("%d: %#v", i, p)
+		}
+		return
+	}
+	if !accept(packets[0]) {
+		c.printLocation(t)
+		c.errorF("%s", want)
+		c.errorF("But got: %#v", packets[0])
+	}
+}
+
+// ShouldReceiveCount will fire a test error unless the given function sends
+// exactly n datagrams over UDP, whatever they hold.
+func (s *Server) ShouldReceiveCount(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkCount(t, n, body, opts, "exactly", func(got int) bool { return got == n })
+}
+
+// ShouldReceiveCount calls Server.ShouldReceiveCount on the default server.
+func ShouldReceiveCount(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveCount(t, n, body, opts...)
+}
+
+// ShouldReceiveAtLeast will fire a test error if the given function sends
+// fewer than n datagrams over UDP.
+func (s *Server) ShouldReceiveAtLeast(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkCount(t, n, body, opts, "at least", func(got int) bool { return got >= n })
+}
+
+// ShouldReceiveAtLeast calls Server.ShouldReceiveAtLeast on the default server.
+func ShouldReceiveAtLeast(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAtLeast(t, n, body, opts...)
+}
+
+// ShouldReceiveAtMost will fire a test error if the given function sends more
+// than n datagrams over UDP.
+func (s *Server) ShouldReceiveAtMost(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkCount(t, n, body, opts, "at most", func(got int) bool { return got <= n })
+}
+
+// ShouldReceiveAtMost calls Server.ShouldReceiveAtMost on the default server.
+func ShouldReceiveAtMost(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAtMost(t, n, body, opts...)
+}
+
+// checkCount fires a test error unless ok accepts the number of datagrams the
+// given function sends. bound describes n in the failure message.
+func (s *Server) checkCount(t TestingT, n int, body fn, opts []Option, bound string, ok func(got int) bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	if !ok(len(packets)) {
+		c.printLocation(t)
+		c.errorF("Expected %s %d packets, but got %d", bound, n, len(packets))
+	}
+}
+
+// ShouldReceiveAtLeastNBytes will fire a test error if the datagrams the given
+// function sends over UDP hold fewer than n bytes in all.
+func (s *Server) ShouldReceiveAtLeastNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkBytes(t, body, opts, fmt.Sprintf("at least %d", n), func(got int) bool { return got >= n })
+}
+
+// ShouldReceiveAtLeastNBytes calls Server.ShouldReceiveAtLeastNBytes on the
+// default server.
+func ShouldReceiveAtLeastNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAtLeastNBytes(t, n, body, opts...)
+}
+
+// ShouldReceiveAtMostNBytes will fire a test error if the datagrams the given
+// function sends over UDP hold more than n bytes in all. Together with
+// ShouldReceiveAtLeastNBytes it bounds the total from both sides.
+func (s *Server) ShouldReceiveAtMostNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkBytes(t, body, opts, fmt.Sprintf("at most %d", n), func(got int) bool { return got <= n })
+}
+
+// ShouldReceiveAtMostNBytes calls Server.ShouldReceiveAtMostNBytes on the
+// default server.
+func ShouldReceiveAtMostNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveAtMostNBytes(t, n, body, opts...)
+}
+
+// ShouldReceiveExactNBytes will fire a test error unless the datagrams the
+// given function sends over UDP hold exactly n bytes in all.
+func (s *Server) ShouldReceiveExactNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkBytes(t, body, opts, fmt.Sprintf("exactly %d", n), func(got int) bool { return got == n })
+}
+
+// ShouldReceiveExactNBytes calls Server.ShouldReceiveExactNBytes on the
+// default server.
+func ShouldReceiveExactNBytes(t TestingT, n int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveExactNBytes(t, n, body, opts...)
+}
+
+// ShouldReceiveBetweenNAndMBytes will fire a test error unless the datagrams
+// the given function sends over UDP hold from min to max bytes in all.
+func (s *Server) ShouldReceiveBetweenNAndMBytes(t TestingT, min, max int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	s.checkBytes(t, body, opts, fmt.Sprintf("between %d and %d", min, max), func(got int) bool { return got >= min && got <= max })
+}
+
+// ShouldReceiveBetweenNAndMBytes calls Server.ShouldReceiveBetweenNAndMBytes
+// on the default server.
+func ShouldReceiveBetweenNAndMBytes(t TestingT, min, max int, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveBetweenNAndMBytes(t, min, max, body, opts...)
+}
+
+// checkBytes fires a test error unless ok accepts the total size of the
+// datagrams the given function sends. want describes the accepted sizes in
+// the failure message.
+func (s *Server) checkBytes(t TestingT, body fn, opts []Option, want string, ok func(got int) bool) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	total := 0
+	for _, p := range packets {
+		total += len(p.Payload)
+	}
+	if !ok(total) {
+		c.printLocation(t)
+		c.errorF("Expected %s bytes, but got %d in %d packets", want, total, len(packets))
+	}
+}
+
+// ShouldReceiveOnlyPackets will fire a test error unless the datagrams the
+// given function sends over UDP are exactly the given strings, in any order.
+// A string given twice must arrive twice.
+func (s *Server) ShouldReceiveOnlyPackets(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	got := payloadStrings(c.capturePackets(t, body, newCallConfig(opts)))
+	missing, unexpected := multisetDiff(expected, got)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return
+	}
+	c.printLocation(t)
+	if len(missing) > 0 {
+		c.errorF("Missing packets: %#v", missing)
+	}
+	if len(unexpected) > 0 {
+		c.errorF("Unexpected packets: %#v", unexpected)
 	}
 }
 
-func ReceiveString(t TestingT, body fn) string {
-	return getMessage(t, body, true)
+// ShouldReceiveOnlyPackets calls Server.ShouldReceiveOnlyPackets on the default
+// server.
+func ShouldReceiveOnlyPackets(t TestingT, expected []string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveOnlyPackets(t, expected, body, opts...)
+}
+
+// multisetDiff returns the strings of want that got lacks and those of got
+// that want lacks, counting duplicates, each in the order they were given.
+func multisetDiff(want, got []string) (missing, extra []string) {
+	counts := map[string]int{}
+	for _, g := range got {
+		counts[g]++
+	}
+	for _, w := range want {
+		if counts[w] > 0 {
+			counts[w]--
+		} else {
+			missing = append(missing, w)
+		}
+	}
+	for _, g := range got {
+		if counts[g] > 0 {
+			counts[g]--
+			extra = append(extra, g)
+		}
+	}
+	return missing, extra
+}
+
+// ReceiveFrom returns everything the given function sends over UDP,
+// concatenated, and the address the first packet was sent from. Use
+// ReceiveCapture to tell the senders of several packets apart.
+func (s *Server) ReceiveFrom(t TestingT, body fn, opts ...Option) (data []byte, from net.Addr) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	for _, p := range packets {
+		data = append(data, p.Payload...)
+	}
+	if len(packets) > 0 {
+		from = packets[0].From
+	}
+	return data, from
+}
+
+// ReceiveFrom calls Server.ReceiveFrom on the default server.
+func ReceiveFrom(t TestingT, body fn, opts ...Option) (data []byte, from net.Addr) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveFrom(t, body, opts...)
+}
+
+// ShouldReceiveFrom will fire a test error unless the given function sends a
+// packet containing the given string over UDP from expectedAddr. Only the
+// first packet containing the string is checked.
+func (s *Server) ShouldReceiveFrom(t TestingT, expectedAddr string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	want, err := net.ResolveUDPAddr("udp", expectedAddr)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	packets := c.capturePackets(t, body, newCallConfig(opts))
+	for _, p := range packets {
+		if !strings.Contains(string(p.Payload), expected) {
+			continue
+		}
+		if !p.From.IP.Equal(want.IP) || p.From.Port != want.Port {
+			c.printLocation(t)
+			c.errorF("Expected %#v from: %s", expected, want)
+			c.errorF("But it came from: %s", p.From)
+		}
+		return
+	}
+	c.printLocation(t)
+	c.errorF("Expected: %#v", expected)
+	c.errorF("But got packets: %#v", payloadStrings(packets))
+}
+
+// ShouldReceiveFrom calls Server.ShouldReceiveFrom on the default server.
+func ShouldReceiveFrom(t TestingT, expectedAddr string, expected string, body fn, opts ...Option) {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	defaultServer.ShouldReceiveFrom(t, expectedAddr, expected, body, opts...)
+}
+
+// payloadStrings returns the payload of each packet as a string.
+func payloadStrings(packets []Packet) []string {
+	payloads := make([]string, len(packets))
+	for i, p := range packets {
+		payloads[i] = string(p.Payload)
+	}
+	return payloads
+}
+
+func (s *Server) ReceiveString(t TestingT, body fn, opts ...Option) string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	return c.captureMessage(t, body, true, newCallConfig(opts))
+}
+
+// ReceiveString calls Server.ReceiveString on the default server.
+func ReceiveString(t TestingT, body fn, opts ...Option) string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveString(t, body, opts...)
+}
+
+// ReceiveStringContext is ReceiveString, but stops reading as soon as ctx is
+// done, however busy the sender still is, and returns what was read so far.
+// The body itself is not interrupted.
+func (s *Server) ReceiveStringContext(t TestingT, ctx context.Context, body fn, opts ...Option) string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	c := s.newCall()
+	defer c.emitLog(t)
+	cfg := newCallConfig(opts)
+	cfg.ctx = ctx
+	return c.captureMessage(t, body, true, cfg)
+}
+
+// ReceiveStringContext calls Server.ReceiveStringContext on the default
+// server.
+func ReceiveStringContext(t TestingT, ctx context.Context, body fn, opts ...Option) string {
+	if h, ok := t.(HelperT); ok {
+		h.Helper()
+	}
+	return defaultServer.ReceiveStringContext(t, ctx, body, opts...)
 }
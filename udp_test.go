@@ -1,26 +1,138 @@
 package udp
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 var (
-	testAddr = ":8126"
+	testAddr = ":0"
 )
 
 func setup(t *testing.T) net.Conn {
-	udpClient, err := net.DialTimeout("udp", testAddr, time.Second)
+	SetAddr(testAddr)
+
+	udpClient, err := net.DialTimeout("udp", Addr(), time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	SetAddr(testAddr)
-
 	return udpClient
 }
 
+// freeAddrs returns n loopback addresses whose ports were free a moment ago,
+// for tests that need a listener bound afresh on every assertion.
+func freeAddrs(t *testing.T, n int) []string {
+	addrs := make([]string, n)
+	for i := range addrs {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		addrs[i] = conn.LocalAddr().String()
+	}
+	return addrs
+}
+
+// fakeT records failures instead of failing the real test, so assertions can
+// be checked for the errors they report.
+type fakeT struct {
+	errors []string
+	fatals []string
+	logs   []string
+}
+
+func (f *fakeT) Logf(format string, args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Error(args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprint(args...))
+}
+
+func (f *fakeT) Fatal(args ...interface{}) {
+	f.fatals = append(f.fatals, fmt.Sprint(args...))
+}
+
+// helperT counts the assertion's calls to Helper.
+type helperT struct {
+	fakeT
+	helpers int
+}
+
+func (h *helperT) Helper() {
+	h.helpers++
+}
+
+func TestAssertionsMarkHelpers(t *testing.T) {
+	udpClient := setup(t)
+
+	ht := &helperT{}
+	ShouldReceive(ht, "foo", func() {
+		udpClient.Write([]byte("bar"))
+	})
+	if len(ht.errors) != 1 || ht.helpers == 0 {
+		t.Errorf("Expected the failing assertion to mark itself a helper, got %d calls", ht.helpers)
+	}
+	if strings.Contains(ht.errors[0], "At: ") {
+		t.Errorf("Expected no location in the message when t reports it, got %q", ht.errors[0])
+	}
+}
+
+// expectFoo and expectFooOnce wrap ShouldReceive in two levels of the
+// caller's own helpers.
+func expectFoo(t *testing.T, body fn) {
+	t.Helper()
+	expectFooOnce(t, body)
+}
+
+func expectFooOnce(t *testing.T, body fn) {
+	t.Helper()
+	ShouldReceive(t, "foo", body)
+}
+
+func TestHelperLocationFails(t *testing.T) {
+	if os.Getenv("UDP_TEST_HELPER_LOCATION") == "" {
+		t.Skip("run by TestHelperLocation")
+	}
+	setup(t)
+	expectFoo(t, func() {}) // the reported line
+}
+
+func TestHelperLocation(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperLocationFails$", "-test.v")
+	cmd.Env = append(os.Environ(), "UDP_TEST_HELPER_LOCATION=1")
+	out, _ := cmd.CombinedOutput()
+
+	src, err := ioutil.ReadFile("udp_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := 0
+	for i, l := range strings.Split(string(src), "\n") {
+		if strings.HasSuffix(l, "// the reported line") {
+			line = i + 1
+		}
+	}
+	if expected := fmt.Sprintf("udp_test.go:%d: Expected: \"foo\"", line); !strings.Contains(string(out), expected) {
+		t.Errorf("Expected the failure at %q, got:\n%s", expected, out)
+	}
+}
+
 func TestAll(t *testing.T) {
 	udpClient := setup(t)
 
@@ -38,9 +150,9 @@ func TestAll(t *testing.T) {
 		shouldEquals := values[2].(bool)
 		shouldContains := values[3].(bool)
 
-		got, equals, contains := get(t, shouldGet, func() {
+		got, equals, contains := defaultServer.newCall().get(t, shouldGet, func() {
 			udpClient.Write([]byte(sendString))
-		}, true)
+		}, true, newCallConfig(nil))
 
 		if got != sendString {
 			t.Errorf("Should've got %#v but got %#v", sendString, got)
@@ -61,6 +173,10 @@ func TestAll(t *testing.T) {
 		udpClient.Write([]byte("foo"))
 	})
 
+	ShouldReceiveSomethingButNot(t, "bar", func() {
+		udpClient.Write([]byte("foo"))
+	})
+
 	ShouldReceive(t, "foo", func() {
 		udpClient.Write([]byte("barfoo"))
 	})
@@ -92,12 +208,778 @@ func TestAll(t *testing.T) {
 func TestRaceConditionInReadingResults(t *testing.T) {
 	udpClient := setup(t)
 
+	// The packets are only sent once the body has returned, so only the
+	// linger keeps the capture open long enough to see them.
+	returned := make(chan struct{})
 	ShouldReceiveAllAndNotReceiveAny(t, []string{"foo", "bar", "biz"}, []string{"fooby", "bars"}, func() {
-		time.Sleep(time.Millisecond * 100)
+		defer close(returned)
+		go func() {
+			<-returned
+			udpClient.Write([]byte("foo"))
+			udpClient.Write([]byte("biz"))
+			udpClient.Write([]byte("bar"))
+		}()
+	}, WithLinger(time.Millisecond*200))
+}
+
+func TestLingerCaptureTime(t *testing.T) {
+	udpClient := setup(t)
+
+	started := time.Now()
+	ShouldReceiveAllAndNotReceiveAny(t, []string{"foo"}, nil, func() {
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			udpClient.Write([]byte("foo"))
+		}()
+	}, WithLinger(time.Millisecond*50))
+	if elapsed := time.Since(started); elapsed < time.Millisecond*50 {
+		t.Errorf("Expected the capture to linger for 50ms, but it took %v", elapsed)
+	}
+
+	ft := &fakeT{}
+	ShouldReceiveAllAndNotReceiveAny(ft, []string{"foo"}, nil, func() {
+		go func() {
+			time.Sleep(time.Millisecond * 20)
+			udpClient.Write([]byte("foo"))
+		}()
+	})
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected the late packet to be missed without linger, got %#v", ft.errors)
+	}
+	time.Sleep(time.Millisecond * 30)
+}
+
+func TestShouldReceiveSomethingButNot(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldReceiveSomethingButNot(ft, "foo", func() {})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "ShouldNotReceiveOnly") {
+		t.Errorf("Expected an empty capture error, got %#v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	ShouldReceiveSomethingButNot(ft, "foo", func() {
 		udpClient.Write([]byte("foo"))
-		time.Sleep(time.Millisecond * 200)
-		udpClient.Write([]byte("biz"))
-		time.Sleep(time.Millisecond * 500)
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Expected not to get") {
+		t.Errorf("Expected an exact match error, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveFieldsInOrder(t *testing.T) {
+	udpClient := setup(t)
+	fields := []string{`\d+`, `[a-z.]+`, `[0-9.]+`}
+
+	ShouldReceiveFieldsInOrder(t, fields, func() {
+		udpClient.Write([]byte("1500000000 api.latency 12.5\n1500000001 api.hits 3\n"))
+	})
+
+	ShouldReceiveFieldsInOrder(t, fields, func() {
+		udpClient.Write([]byte("1500000000 api.latency 12.5 host=a"))
+	}, WithExtraFields())
+
+	ft := &fakeT{}
+	ShouldReceiveFieldsInOrder(ft, fields, func() {
+		udpClient.Write([]byte("api.latency 1500000000 12.5\n1500000000 api.hits 3 host=a"))
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "field 0: expected") || !strings.Contains(ft.errors[0], "extra fields") {
+		t.Errorf("Expected field order and extra field errors, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveQUICInitialWithSNI(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldReceiveQUICInitialWithSNI(ft, "telemetry.example.com", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte{0x80, 0, 0, 0, 0, 0, 0})
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "1 version negotiation") ||
+		!strings.Contains(ft.errors[0], "1 non-QUIC") {
+		t.Errorf("Expected a failure counting skipped packets, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveConnlessPackets(t *testing.T) {
+	udpClient := setup(t)
+	otherClient, err := net.Dial("udp", Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherClient.Close()
+
+	ShouldReceiveConnlessPackets(t, func() {
+		udpClient.Write([]byte("foo"))
+		otherClient.Write([]byte("bar"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveConnlessPackets(ft, func() {})
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected a no data error, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveEmptyPacket(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveEmptyPacket(t, func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte{})
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveEmptyPacket(ft, func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected a missing empty packet error, got %#v", ft.errors)
+	}
+}
+
+func TestEmptyDatagramDoesNotEndMessage(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveOnly(t, "foobar", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte{})
+		udpClient.Write([]byte("bar"))
+	})
+
+	ShouldReceiveNothing(t, func() {
+		udpClient.Write([]byte{})
+	})
+}
+
+func TestFailureMessageFormatting(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldReceiveOnly(ft, "foo", func() {
 		udpClient.Write([]byte("bar"))
 	})
+	if len(ft.errors) != 1 {
+		t.Fatalf("Expected one error, got %#v", ft.errors)
+	}
+
+	lines := strings.Split(ft.errors[0], "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "At: ") || !strings.Contains(lines[0], "udp_test.go:") {
+		t.Errorf("Expected a location line, got %#v", lines)
+	}
+	if got := strings.Join(lines[1:], "\n"); got != "Expected: \"foo\"\nBut got: \"bar\"" {
+		t.Errorf("Expected formatted arguments, got %#v", got)
+	}
+}
+
+func TestLargeFailureDoesNotDelayNextAssertion(t *testing.T) {
+	udpClient := setup(t)
+	chunk := []byte(strings.Repeat("x", 50000))
+
+	ft := &fakeT{}
+	ShouldReceive(ft, "foo", func() {
+		for i := 0; i < 100; i++ {
+			udpClient.Write(chunk)
+		}
+	})
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected the large capture to fail, got %d errors", len(ft.errors))
+	}
+
+	ShouldReceiveOnly(t, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+}
+
+func TestConcurrentLogBuf(t *testing.T) {
+	c := defaultServer.newCall()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ft := &fakeT{}
+			for j := 0; j < 100; j++ {
+				c.errorF("failure %d", j)
+				c.emitLog(ft)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPanickingBodyReleasesListener(t *testing.T) {
+	udpClient := setup(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected the body's panic to propagate, got %#v", r)
+			}
+		}()
+		ShouldReceive(t, "foo", func() {
+			panic("boom")
+		})
+	}()
+
+	ShouldReceiveOnly(t, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+}
+
+func TestShouldReceiveAllReportsNoData(t *testing.T) {
+	setup(t)
+
+	ft := &fakeT{}
+	_, _, line, _ := runtime.Caller(0)
+	ShouldReceiveAll(ft, []string{"foo", "bar"}, func() {})
+	line++
+	if len(ft.errors) != 1 {
+		t.Fatalf("Expected one error, got %#v", ft.errors)
+	}
+	lines := strings.Split(ft.errors[0], "\n")
+	if !strings.HasSuffix(lines[1], fmt.Sprintf("udp_test.go:%d", line)) {
+		t.Errorf("Expected the location of the assertion, got %#v", lines[1])
+	}
+	if len(lines) != 6 || lines[2] != "No data was received" || lines[3] != `Expected to find: "foo"` {
+		t.Errorf("Expected a no data line before the missing strings, got %#v", lines)
+	}
+}
+
+func TestShouldReceiveNothingReportsReadErrors(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft := &fakeT{}
+	s.ShouldReceiveNothing(ft, func() {
+		s.Close()
+	})
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "Error reading udp data") {
+		t.Errorf("Expected the read error to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestStopReportsCloseErrors(t *testing.T) {
+	// A server that binds its listener for each assertion, unlike SetAddr(":0").
+	a := "127.0.0.1:0"
+	s := &Server{addr: &a}
+
+	ft := &fakeT{}
+	c := s.newCall()
+	c.start(ft, &callConfig{})
+	c.listener.Close()
+	c.stop(ft)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "close udp") {
+		t.Errorf("Expected the close error to be reported, got %#v", ft.errors)
+	}
+	if len(ft.fatals) != 0 {
+		t.Errorf("Expected the close error not to be fatal, got %#v", ft.fatals)
+	}
+}
+
+func TestStartWithoutSetAddr(t *testing.T) {
+	savedAddr, savedConn := defaultServer.addr, defaultServer.conn
+	defaultServer.addr, defaultServer.conn = nil, nil
+	defer func() { defaultServer.addr, defaultServer.conn = savedAddr, savedConn }()
+
+	ft := &fakeT{}
+	defaultServer.newCall().start(ft, &callConfig{})
+	if len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "SetAddr must be called") {
+		t.Errorf("Expected a fatal error asking for SetAddr, got %#v", ft.fatals)
+	}
+
+	ft = &fakeT{}
+	ran := false
+	ShouldReceive(ft, "foo", func() { ran = true })
+	ShouldReceivePacket(ft, "foo", func() { ran = true })
+	if ran || len(ft.fatals) != 2 || ft.fatals[0] != "udp: SetAddr must be called before any assertion" || len(ft.errors) != 2 {
+		t.Errorf("Expected each assertion to fail fast asking for SetAddr, got %#v and %#v", ft.fatals, ft.errors)
+	}
+}
+
+func TestWithWarnOnNoData(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldNotReceiveOnly(ft, "foo", func() {}, WithWarnOnNoData())
+	ShouldNotReceive(ft, "foo", func() {}, WithWarnOnNoData())
+	ShouldNotReceiveAny(ft, []string{"foo"}, func() {}, WithWarnOnNoData())
+	ShouldNotReceive(ft, "foo", func() {})
+	ShouldNotReceive(ft, "foo", func() {
+		udpClient.Write([]byte("bar"))
+	}, WithWarnOnNoData())
+	if len(ft.errors) != 0 || len(ft.logs) != 3 || !strings.Contains(ft.logs[0], "udp_test.go:") {
+		t.Errorf("Expected a warning for each empty capture, got %#v %#v", ft.errors, ft.logs)
+	}
+}
+
+func BenchmarkShouldReceiveAll(b *testing.B) {
+	SetAddr(testAddr)
+	udpClient, err := net.Dial("udp", Addr())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer udpClient.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ShouldReceiveAll(b, []string{"foo"}, func() {
+			udpClient.Write([]byte("foo"))
+		})
+	}
+}
+
+func TestErrorFExpandsArguments(t *testing.T) {
+	c := defaultServer.newCall()
+	c.errorF("Expected packet %d to have TTL %d, but got %d", 1, 64, 2)
+	c.errorF("Expected: %#v", "foo")
+	ft := &fakeT{}
+	c.emitLog(ft)
+	if len(ft.errors) != 1 || ft.errors[0] != "Expected packet 1 to have TTL 64, but got 2\nExpected: \"foo\"" {
+		t.Errorf("Expected each argument to fill its own verb, got %#v", ft.errors)
+	}
+}
+
+func TestAssertionMessagesRenderArguments(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldNotReceive(ft, "foo", func() {
+		udpClient.Write([]byte("foobar"))
+	})
+	ShouldReceiveAll(ft, []string{"foo", "baz"}, func() {
+		udpClient.Write([]byte("foobar"))
+	})
+	if len(ft.errors) != 2 {
+		t.Fatalf("Expected two errors, got %#v", ft.errors)
+	}
+	for i, want := range []string{
+		"Expected not to find: \"foo\"\nBut got: \"foobar\"",
+		"Expected to find: \"baz\"\nBut got: \"foobar\"",
+	} {
+		lines := strings.SplitN(ft.errors[i], "\n", 2)
+		if len(lines) != 2 || lines[1] != want {
+			t.Errorf("Expected %#v after the location, got %#v", want, ft.errors[i])
+		}
+	}
+}
+
+func TestSetAddrValidates(t *testing.T) {
+	setup(t)
+	defer func() {
+		r := recover()
+		if msg, ok := r.(string); !ok || !strings.Contains(msg, `SetAddr("localhost")`) {
+			t.Errorf("Expected a panic naming the bad address, got %#v", r)
+		}
+		if *defaultServer.addr != testAddr {
+			t.Errorf("Expected the address to be left as it was, got %#v", *defaultServer.addr)
+		}
+	}()
+	SetAddr("localhost")
+}
+
+func TestWithTimeout(t *testing.T) {
+	udpClient := setup(t)
+
+	late := func() {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			udpClient.Write([]byte("late"))
+		}()
+	}
+	ft := &fakeT{}
+	ShouldReceiveOnly(ft, "late", late)
+	if len(ft.errors) != 1 {
+		t.Errorf("Expected the default timeout to miss the late packet, got %#v", ft.errors)
+	}
+	// Let the first late packet arrive and be read by an assertion of its own.
+	ShouldReceiveOnly(t, "late", func() {}, WithTimeout(200*time.Millisecond))
+	ShouldReceiveOnly(t, "late", late, WithTimeout(200*time.Millisecond))
+}
+
+func TestShouldReceivePacket(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceivePacket(t, "foo", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("foo"))
+	})
+	ShouldNotReceivePacket(t, "foobar", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceivePacket(ft, "foobar", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+	ShouldNotReceivePacket(ft, "bar", func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("bar"))
+	})
+	if len(ft.errors) != 2 ||
+		!strings.HasSuffix(ft.errors[0], "Expected a packet: \"foobar\"\nBut got packets: []string{\"foo\", \"bar\"}") ||
+		!strings.HasSuffix(ft.errors[1], "Expected no packet: \"bar\"\nBut got packets: []string{\"foo\", \"bar\"}") {
+		t.Errorf("Expected both assertions to list the packets, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveInOrder(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveInOrder(t, []string{"a", "b", "a"}, func() {
+		udpClient.Write([]byte("xaybza"))
+	})
+	ShouldReceiveInOrder(t, []string{"hello", "data", "more"}, func() {
+		udpClient.Write([]byte("hello"))
+		udpClient.Write([]byte("data more"))
+	})
+	ShouldReceiveInOrder(t, []string{"ab", "bc"}, func() {
+		udpClient.Write([]byte("abc"))
+	})
+	ShouldReceivePacketsInOrder(t, []string{"a", "b"}, func() {
+		udpClient.Write([]byte("a"))
+		udpClient.Write([]byte("noise"))
+		udpClient.Write([]byte("b"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveInOrder(ft, []string{"b", "a", "c", "d"}, func() {
+		udpClient.Write([]byte("abc"))
+	})
+	ShouldReceivePacketsInOrder(ft, []string{"a", "b"}, func() {
+		udpClient.Write([]byte("ab"))
+	})
+	if len(ft.errors) != 2 ||
+		!strings.HasSuffix(ft.errors[0], "Expected \"a\" after \"b\"\nExpected to find: \"d\"\nBut got packets:\n0: \"abc\"") ||
+		!strings.HasSuffix(ft.errors[1], "Expected \"b\" in a packet after \"a\"\nBut got packets: []string{\"ab\"}") {
+		t.Errorf("Expected every violation in one failure, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveTransformed(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveTransformed(t, strings.ToUpper, "FOO", func() {
+		udpClient.Write([]byte("foo"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveTransformed(ft, strings.ToUpper, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Expected: \"foo\"\nBut got: \"foo\"\nTransformed to: \"FOO\"") {
+		t.Errorf("Expected the raw and transformed data to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveOnlyPackets(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveOnlyPackets(t, []string{"x:1|c", "y:2|g", "x:1|c"}, func() {
+		udpClient.Write([]byte("y:2|g"))
+		udpClient.Write([]byte("x:1|c"))
+		udpClient.Write([]byte("x:1|c"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveOnlyPackets(ft, []string{"x:1|c", "x:1|c", "y:2|g"}, func() {
+		udpClient.Write([]byte("x:1|c"))
+		udpClient.Write([]byte("z:3|ms"))
+		udpClient.Write([]byte("y:2|g"))
+		udpClient.Write([]byte("y:2|g"))
+	})
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Missing packets: []string{\"x:1|c\"}\nUnexpected packets: []string{\"z:3|ms\", \"y:2|g\"}") {
+		t.Errorf("Expected the missing and unexpected packets to be listed, got %#v", ft.errors)
+	}
+}
+
+func TestReceiveStringContext(t *testing.T) {
+	udpClient := setup(t)
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	got := ReceiveStringContext(t, ctx, func() {
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(time.Millisecond):
+					udpClient.Write([]byte("x"))
+				}
+			}
+		}()
+	}, WithTimeout(time.Second))
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected reading to stop with the context, but it took %v", elapsed)
+	}
+	if len(got) == 0 {
+		t.Errorf("Expected the data read before the context was done")
+	}
+}
+
+func TestShouldReceiveFrom(t *testing.T) {
+	udpClient := setup(t)
+	otherClient, err := net.Dial("udp", Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherClient.Close()
+
+	data, from := ReceiveFrom(t, func() {
+		udpClient.Write([]byte("foo"))
+		otherClient.Write([]byte("bar"))
+	})
+	if string(data) != "foobar" || from.String() != udpClient.LocalAddr().String() {
+		t.Errorf("Expected the data and the first sender, got %#v from %v", string(data), from)
+	}
+
+	ShouldReceiveFrom(t, otherClient.LocalAddr().String(), "bar", func() {
+		udpClient.Write([]byte("foo"))
+		otherClient.Write([]byte("bar"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveFrom(ft, udpClient.LocalAddr().String(), "bar", func() {
+		otherClient.Write([]byte("bar"))
+	})
+	want := fmt.Sprintf("Expected \"bar\" from: %s\nBut it came from: %s", udpClient.LocalAddr(), otherClient.LocalAddr())
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) {
+		t.Errorf("Expected the wrong sender to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveComparedWith(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveComparedWith(t, "FOO", strings.EqualFold, func() {
+		udpClient.Write([]byte("foo"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveComparedWith(ft, "bar", strings.EqualFold, func() {
+		udpClient.Write([]byte("foo"))
+	})
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Expected: \"bar\"\nBut got: \"foo\"") {
+		t.Errorf("Expected the comparison to fail, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveInOrderListsPackets(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldReceiveInOrder(ft, []string{"data", "hello", "more"}, func() {
+		udpClient.Write([]byte("hello"))
+		udpClient.Write([]byte("da"))
+		udpClient.Write([]byte("ta"))
+	})
+	want := "Expected to find: \"data\"\nExpected to find: \"more\"\n" +
+		"But got packets:\n0: \"hello\"\n1: \"da\"\n2: \"ta\""
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) {
+		t.Errorf("Expected the broken expectations and the packet sequence, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveInOrderReportsOutOfOrder(t *testing.T) {
+	udpClient := setup(t)
+
+	ft := &fakeT{}
+	ShouldReceiveInOrder(ft, []string{"foo", "bar"}, func() {
+		udpClient.Write([]byte("bar"))
+		udpClient.Write([]byte("foo"))
+	})
+	want := "Expected \"bar\" after \"foo\"\n" +
+		"But got packets:\n0: \"bar\"\n1: \"foo\""
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) {
+		t.Errorf("Expected the out of order item to be reported, got %#v", ft.errors)
+	}
+
+	ShouldReceiveAll(t, []string{"foo", "bar"}, func() {
+		udpClient.Write([]byte("bar"))
+		udpClient.Write([]byte("foo"))
+	})
+}
+
+func TestAddr(t *testing.T) {
+	savedAddr, savedConn := defaultServer.addr, defaultServer.conn
+	defaultServer.addr, defaultServer.conn = nil, nil
+	if got := Addr(); got != "" {
+		t.Errorf("Expected no address before SetAddr, got %#v", got)
+	}
+	defaultServer.addr, defaultServer.conn = savedAddr, savedConn
+
+	SetAddr("127.0.0.1:0")
+	_, port, err := net.SplitHostPort(Addr())
+	if err != nil || port == "0" {
+		t.Errorf("Expected the bound port, got %#v (%v)", Addr(), err)
+	}
+}
+
+func TestListenOnFreePort(t *testing.T) {
+	first, err := ListenOnFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ListenOnFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("Expected two free ports, got %d twice", first)
+	}
+
+	udpClient, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udpClient.Close()
+	ShouldReceiveOnly(t, "foo", func() {
+		udpClient.Write([]byte("foo"))
+	})
+}
+
+func TestShouldReceiveExactly(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveExactly(t, 2, "hits:1|c", func() {
+		udpClient.Write([]byte("hits:1|c"))
+		udpClient.Write([]byte("hits:1|c"))
+		udpClient.Write([]byte("misses:1|c"))
+	})
+	ShouldReceiveExactly(t, 0, "errors", func() {
+		udpClient.Write([]byte("hits:1|c"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveExactly(ft, 1, "hits", func() {
+		udpClient.Write([]byte("hits:1|c"))
+		udpClient.Write([]byte("misses:1|c"))
+		udpClient.Write([]byte("hits:1|c"))
+	})
+	want := "Expected 1 packets containing: \"hits\"\nBut got 2, out of:\n" +
+		"0: \"hits:1|c\" (match)\n1: \"misses:1|c\"\n2: \"hits:1|c\" (match)"
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], want) {
+		t.Errorf("Expected both counts and the packets, got %#v", ft.errors)
+	}
+}
+
+func TestWithTimeoutReachesEveryAssertion(t *testing.T) {
+	udpClient := setup(t)
+
+	late := func(payload string) fn {
+		return func() {
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				udpClient.Write([]byte(payload))
+			}()
+		}
+	}
+	ShouldReceiveConnlessPackets(t, late("foo"), WithTimeout(200*time.Millisecond))
+	ShouldReceiveEmptyPacket(t, late(""), WithTimeout(200*time.Millisecond))
+}
+
+func TestShouldReceiveAtLeastNBytes(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		udpClient.Write([]byte("foo"))
+		udpClient.Write([]byte("barbaz"))
+	}
+
+	ShouldReceiveAtLeastNBytes(t, 9, send)
+
+	ft := &fakeT{}
+	ShouldReceiveAtLeastNBytes(ft, 10, send)
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Expected at least 10 bytes, but got 9 in 2 packets") {
+		t.Errorf("Expected the byte count to be reported, got %#v", ft.errors)
+	}
+
+	ShouldReceiveAtMostNBytes(t, 9, send)
+
+	ft = &fakeT{}
+	ShouldReceiveAtMostNBytes(ft, 8, send)
+	if len(ft.errors) != 1 || !strings.HasSuffix(ft.errors[0], "Expected at most 8 bytes, but got 9 in 2 packets") {
+		t.Errorf("Expected the byte count to be reported, got %#v", ft.errors)
+	}
+
+	ShouldReceiveBetweenNAndMBytes(t, 9, 9, send)
+	ShouldReceiveExactNBytes(t, 9, send)
+
+	ft = &fakeT{}
+	ShouldReceiveBetweenNAndMBytes(ft, 10, 20, send)
+	ShouldReceiveBetweenNAndMBytes(ft, 1, 8, send)
+	ShouldReceiveExactNBytes(ft, 8, send)
+	if len(ft.errors) != 3 || !strings.HasSuffix(ft.errors[0], "Expected between 10 and 20 bytes, but got 9 in 2 packets") ||
+		!strings.HasSuffix(ft.errors[1], "Expected between 1 and 8 bytes, but got 9 in 2 packets") ||
+		!strings.HasSuffix(ft.errors[2], "Expected exactly 8 bytes, but got 9 in 2 packets") {
+		t.Errorf("Expected the byte count and range to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveOnce(t *testing.T) {
+	udpClient := setup(t)
+
+	ShouldReceiveOnce(t, "hits:1|c", func() {
+		udpClient.Write([]byte("hits:1|c"))
+	})
+	ShouldReceiveContainsOnce(t, "hits", func() {
+		udpClient.Write([]byte("hits:1|c"))
+	})
+
+	ft := &fakeT{}
+	ShouldReceiveOnce(ft, "hits:1|c", func() {
+		udpClient.Write([]byte("hits:1|c"))
+		udpClient.Write([]byte("hits:1|c"))
+	})
+	ShouldReceiveContainsOnce(ft, "load", func() {
+		udpClient.Write([]byte("hits:1|c"))
+	})
+	if len(ft.errors) != 2 ||
+		!strings.HasSuffix(ft.errors[0], "Expected 1 packet, but got 2:\n0: \"hits:1|c\"\n1: \"hits:1|c\"") ||
+		!strings.HasSuffix(ft.errors[1], "Expected the packet to contain: \"load\"\nBut got: \"hits:1|c\"") {
+		t.Errorf("Expected the duplicate and the mismatch to be reported, got %#v", ft.errors)
+	}
+}
+
+func TestShouldReceiveCount(t *testing.T) {
+	udpClient := setup(t)
+	send := func() {
+		for i := 0; i < 3; i++ {
+			udpClient.Write([]byte("batch"))
+		}
+	}
+
+	ShouldReceiveCount(t, 3, send)
+	ShouldReceiveAtLeast(t, 3, send)
+	ShouldReceiveAtMost(t, 3, send)
+
+	ft := &fakeT{}
+	ShouldReceiveCount(ft, 2, send)
+	ShouldReceiveAtLeast(ft, 4, send)
+	ShouldReceiveAtMost(ft, 2, send)
+	if len(ft.errors) != 3 {
+		t.Fatalf("Expected three failures, got %#v", ft.errors)
+	}
+	for i, want := range []string{
+		"Expected exactly 2 packets, but got 3",
+		"Expected at least 4 packets, but got 3",
+		"Expected at most 2 packets, but got 3",
+	} {
+		if !strings.HasSuffix(ft.errors[i], want) {
+			t.Errorf("Expected %#v, got %#v", want, ft.errors[i])
+		}
+	}
 }
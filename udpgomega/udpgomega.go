@@ -0,0 +1,139 @@
+// Package udpgomega adapts UDP captures to Gomega, so that a Ginkgo spec can
+// assert on the packets it sends with Expect rather than the TestingT based
+// assertions:
+//
+//	Expect(udpgomega.Receive(func() {
+//		client.Flush()
+//	})).To(udpgomega.ContainPacket("hits:1|c"))
+//
+// The matchers implement the methods of gomega's types.GomegaMatcher without
+// importing it, so this package adds no dependency.
+package udpgomega
+
+import (
+	"fmt"
+	"strings"
+
+	udp "github.com/urjitbhatia/go-udp-testing"
+)
+
+// GomegaMatcher has the methods of gomega's types.GomegaMatcher, which the
+// matchers of this package implement.
+type GomegaMatcher interface {
+	Match(actual interface{}) (success bool, err error)
+	FailureMessage(actual interface{}) (message string)
+	NegatedFailureMessage(actual interface{}) (message string)
+}
+
+// Received holds the datagrams captured by Receive, in arrival order.
+type Received struct {
+	Packets []string
+
+	capture collector
+}
+
+// Receive captures every datagram the given function sends to the address set
+// with udp.SetAddr. Errors capturing are reported by the matchers the result
+// is given to.
+func Receive(body func()) *Received {
+	r := &Received{}
+	for _, p := range udp.ReceiveCapture(&r.capture, body).Packets {
+		r.Packets = append(r.Packets, string(p.Payload))
+	}
+	return r
+}
+
+// collector is a udp.TestingT holding on to capture errors until a matcher
+// reports them.
+type collector struct {
+	errors []string
+}
+
+func (c *collector) Errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+func (c *collector) Error(args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprint(args...))
+}
+
+func (c *collector) Fatal(args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprint(args...))
+}
+
+// received returns actual as a *Received, or an error if it isn't one or its
+// capture failed.
+func received(actual interface{}) (*Received, error) {
+	r, ok := actual.(*Received)
+	if !ok {
+		return nil, fmt.Errorf("udpgomega matchers expect a *udpgomega.Received, got %T", actual)
+	}
+	if len(r.capture.errors) > 0 {
+		return nil, fmt.Errorf("capture failed: %s", strings.Join(r.capture.errors, "\n"))
+	}
+	return r, nil
+}
+
+func packets(actual interface{}) []string {
+	if r, ok := actual.(*Received); ok {
+		return r.Packets
+	}
+	return nil
+}
+
+type containPacketMatcher struct {
+	expected string
+}
+
+// ContainPacket succeeds if one of the datagrams received is exactly
+// expected, as udp.ShouldReceivePacket checks.
+func ContainPacket(expected string) GomegaMatcher {
+	return containPacketMatcher{expected}
+}
+
+func (m containPacketMatcher) Match(actual interface{}) (bool, error) {
+	r, err := received(actual)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range r.Packets {
+		if p == m.expected {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m containPacketMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected packets\n\t%#v\nto contain packet\n\t%#v", packets(actual), m.expected)
+}
+
+func (m containPacketMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected packets\n\t%#v\nnot to contain packet\n\t%#v", packets(actual), m.expected)
+}
+
+type equalPayloadMatcher struct {
+	expected string
+}
+
+// EqualPayload succeeds if the datagrams received, concatenated, are exactly
+// expected, as udp.ShouldReceiveOnly checks.
+func EqualPayload(expected string) GomegaMatcher {
+	return equalPayloadMatcher{expected}
+}
+
+func (m equalPayloadMatcher) Match(actual interface{}) (bool, error) {
+	r, err := received(actual)
+	if err != nil {
+		return false, err
+	}
+	return strings.Join(r.Packets, "") == m.expected, nil
+}
+
+func (m equalPayloadMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected payload\n\t%#v\nto equal\n\t%#v", strings.Join(packets(actual), ""), m.expected)
+}
+
+func (m equalPayloadMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected payload\n\t%#v\nnot to equal\n\t%#v", strings.Join(packets(actual), ""), m.expected)
+}
@@ -0,0 +1,62 @@
+package udpgomega
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	udp "github.com/urjitbhatia/go-udp-testing"
+)
+
+const testAddr = ":0"
+
+func dial(t *testing.T) net.Conn {
+	udp.SetAddr(testAddr)
+	conn, err := net.Dial("udp", udp.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestMatchers(t *testing.T) {
+	conn := dial(t)
+	defer conn.Close()
+	r := Receive(func() {
+		conn.Write([]byte("hits:1|c"))
+		conn.Write([]byte("load:2|g"))
+	})
+
+	for _, tc := range []struct {
+		m        GomegaMatcher
+		expected bool
+	}{
+		{ContainPacket("hits:1|c"), true},
+		{ContainPacket("hits:1"), false},
+		{EqualPayload("hits:1|cload:2|g"), true},
+		{EqualPayload("hits:1|c"), false},
+	} {
+		if ok, err := tc.m.Match(r); ok != tc.expected || err != nil {
+			t.Errorf("Expected %#v to match %v, got %v, %v", tc.m, tc.expected, ok, err)
+		}
+	}
+
+	if msg := ContainPacket("foo").FailureMessage(r); msg != "Expected packets\n\t[]string{\"hits:1|c\", \"load:2|g\"}\nto contain packet\n\t\"foo\"" {
+		t.Errorf("Unexpected failure message %q", msg)
+	}
+	if msg := EqualPayload("foo").NegatedFailureMessage(r); msg != "Expected payload\n\t\"hits:1|cload:2|g\"\nnot to equal\n\t\"foo\"" {
+		t.Errorf("Unexpected negated failure message %q", msg)
+	}
+}
+
+func TestMatcherErrors(t *testing.T) {
+	if _, err := ContainPacket("foo").Match("foo"); err == nil || !strings.Contains(err.Error(), "expect a *udpgomega.Received, got string") {
+		t.Errorf("Expected a wrong actual value to be an error, got %v", err)
+	}
+
+	r := &Received{}
+	r.capture.Fatal("udp: SetAddr must be called before any assertion")
+	if _, err := EqualPayload("").Match(r); err == nil || !strings.Contains(err.Error(), "capture failed: udp: SetAddr must be called") {
+		t.Errorf("Expected a failed capture to be an error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Package udpmock adapts UDP captures to gomock, so that expectations on the
+// packets a test sends are declared, ordered and verified like those of any
+// other mock sharing the same gomock.Controller.
+//
+//	sink := udpmock.NewMockUDPSink(ctrl)
+//	first := sink.EXPECT().Packet(udpmock.ContainsStr("handshake"))
+//	sink.EXPECT().Packet(udpmock.MatchesRe(`lat:\d+`)).Times(2).After(first)
+//	sink.Run(func() { client.Start() })
+//	sink.VerifyAndClose(t)
+package udpmock
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	udp "github.com/urjitbhatia/go-udp-testing"
+)
+
+// MockUDPSink is a gomock mock whose Packet method is called once for every
+// datagram received while Run is capturing, in arrival order.
+type MockUDPSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockUDPSinkMockRecorder
+	capture  collector
+}
+
+// MockUDPSinkMockRecorder records expected calls on a MockUDPSink.
+type MockUDPSinkMockRecorder struct {
+	mock *MockUDPSink
+}
+
+// NewMockUDPSink returns a sink whose expectations are verified by ctrl.
+func NewMockUDPSink(ctrl *gomock.Controller) *MockUDPSink {
+	m := &MockUDPSink{ctrl: ctrl}
+	m.recorder = &MockUDPSinkMockRecorder{m}
+	return m
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUDPSink) EXPECT() *MockUDPSinkMockRecorder {
+	return m.recorder
+}
+
+// Packet reports one received datagram to the controller.
+func (m *MockUDPSink) Packet(payload string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Packet", payload)
+}
+
+// Packet indicates an expected datagram, matched by payload.
+func (mr *MockUDPSinkMockRecorder) Packet(payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Packet", reflect.TypeOf((*MockUDPSink)(nil).Packet), payload)
+}
+
+// Run captures every datagram the given function sends to the address set
+// with udp.SetAddr and delivers each to Packet. An unexpected datagram fails
+// through the controller straight away, like any unexpected mock call.
+func (m *MockUDPSink) Run(body func()) {
+	m.ctrl.T.Helper()
+	for _, p := range udp.ReceiveCapture(&m.capture, body).Packets {
+		m.Packet(string(p.Payload))
+	}
+}
+
+// VerifyAndClose reports any error capturing packets to t, then verifies the
+// controller's expectations.
+func (m *MockUDPSink) VerifyAndClose(t udp.TestingT) {
+	m.ctrl.T.Helper()
+	if h, ok := t.(udp.HelperT); ok {
+		h.Helper()
+	}
+	for _, err := range m.capture.errors {
+		t.Error(err)
+	}
+	m.capture.errors = nil
+	m.ctrl.Finish()
+}
+
+// collector is a udp.TestingT holding on to capture errors until they can be
+// reported by VerifyAndClose.
+type collector struct {
+	errors []string
+}
+
+func (c *collector) Errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+func (c *collector) Error(args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprint(args...))
+}
+
+func (c *collector) Fatal(args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprint(args...))
+}
+
+type containsMatcher string
+
+// ContainsStr matches a payload containing the given string.
+func ContainsStr(substr string) gomock.Matcher {
+	return containsMatcher(substr)
+}
+
+func (m containsMatcher) Matches(x interface{}) bool {
+	s, ok := x.(string)
+	return ok && strings.Contains(s, string(m))
+}
+
+func (m containsMatcher) String() string {
+	return fmt.Sprintf("contains %q", string(m))
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+// MatchesRe matches a payload matching the given regular expression. It panics
+// if the expression doesn't compile.
+func MatchesRe(pattern string) gomock.Matcher {
+	return regexpMatcher{regexp.MustCompile(pattern)}
+}
+
+func (m regexpMatcher) Matches(x interface{}) bool {
+	s, ok := x.(string)
+	return ok && m.re.MatchString(s)
+}
+
+func (m regexpMatcher) String() string {
+	return fmt.Sprintf("matches %q", m.re.String())
+}
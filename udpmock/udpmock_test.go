@@ -0,0 +1,73 @@
+package udpmock
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	udp "github.com/urjitbhatia/go-udp-testing"
+)
+
+const testAddr = ":0"
+
+// reporter collects the controller's failures instead of failing the test.
+type reporter struct {
+	errors []string
+}
+
+func (r *reporter) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *reporter) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *reporter) Helper() {}
+
+func dial(t *testing.T) net.Conn {
+	udp.SetAddr(testAddr)
+	conn, err := net.Dial("udp", udp.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestSinkOrderedExpectations(t *testing.T) {
+	conn := dial(t)
+	defer conn.Close()
+	ctrl := gomock.NewController(t)
+	sink := NewMockUDPSink(ctrl)
+
+	first := sink.EXPECT().Packet(ContainsStr("hello"))
+	sink.EXPECT().Packet(MatchesRe(`lat:\d+`)).Times(2).After(first)
+
+	sink.Run(func() {
+		conn.Write([]byte("hello"))
+		conn.Write([]byte("lat:12|ms"))
+		conn.Write([]byte("lat:7|ms"))
+	})
+	sink.VerifyAndClose(t)
+}
+
+func TestSinkReportsThroughController(t *testing.T) {
+	conn := dial(t)
+	defer conn.Close()
+	r := &reporter{}
+	ctrl := gomock.NewController(r)
+	sink := NewMockUDPSink(ctrl)
+
+	sink.EXPECT().Packet(ContainsStr("hello")).Times(2)
+
+	sink.Run(func() {
+		conn.Write([]byte("hello"))
+	})
+	sink.VerifyAndClose(t)
+
+	if len(r.errors) == 0 || !strings.Contains(r.errors[0], "missing call") {
+		t.Errorf("Expected the missing packet to be reported by the controller, got %#v", r.errors)
+	}
+}